@@ -2,46 +2,194 @@ package adapter
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"nhooyr.io/websocket"
+
 	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/audit"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/certs"
+	"github.com/gastownhall/tmux-adapter/internal/history"
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
 	"github.com/gastownhall/tmux-adapter/internal/rest"
 	"github.com/gastownhall/tmux-adapter/internal/tmux"
 	"github.com/gastownhall/tmux-adapter/internal/ws"
 	"github.com/gastownhall/tmux-adapter/web"
 )
 
+// Config holds the adapter's startup options.
+type Config struct {
+	GtDir          string
+	Port           int
+	AuthToken      string
+	AuthTokensFile string // named, scoped tokens (see auth.LoadTokensFile)
+	SigningKeyFile string // HMAC key for short-lived signed tokens (optional)
+	OriginPatterns []string
+
+	// TLS serves HTTPS/WSS when TLSCert and TLSKey are set. TLSClientCA
+	// enables client-certificate authentication (see auth.Token.Subject).
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
+
+	// UnixSocket additionally serves the same endpoints on a Unix socket,
+	// authenticating callers by peer credentials (see auth.Token.Peer).
+	UnixSocket     string
+	UnixSocketMode os.FileMode
+
+	// Output streaming
+	FlushPolicy   tmux.FlushPolicy
+	WSCompression websocket.CompressionMode
+
+	// InputLockTimeout releases an idle WebSocket input lock.
+	InputLockTimeout time.Duration
+	// WebViewersAttach treats agents streamed by a WebSocket client as
+	// attached when delivering prompts (no SIGWINCH wake).
+	WebViewersAttach bool
+	// ResizePolicy arbitrates window size between WebSocket viewers.
+	ResizePolicy ws.ResizePolicy
+
+	// History enables the persistent transcript store.
+	History          bool
+	HistoryDir       string // defaults to <GtDir>/.tmux-adapter/history
+	HistoryRetention history.Retention
+
+	// RateLimit enables per-caller rate limits (RateLimitsFile overrides
+	// ratelimit.DefaultConfig).
+	RateLimit      bool
+	RateLimitsFile string
+
+	// Discovery admits ad-hoc agents outside gastown's session naming; the
+	// zero value keeps strict gastown mode.
+	Discovery agents.DiscoveryRules
+	// ScanInterval rescans tmux periodically besides on notifications, so
+	// agents that exit inside a live session are noticed; 0 disables it.
+	ScanInterval time.Duration
+
+	// RestartPoliciesFile configures supervised restarts (see
+	// lifecycle.LoadRestartPoliciesFile); agents are not restarted without it.
+	RestartPoliciesFile string
+
+	// AuditLog enables the audit log of mutating actions at this path.
+	AuditLog      string
+	AuditMaxBytes int64
+	AuditMaxFiles int
+}
+
 // Adapter wires together tmux control mode, agent registry, pipe-pane streaming,
 // and the WebSocket server.
 type Adapter struct {
-	ctrl           *tmux.ControlMode
-	registry       *agents.Registry
-	pipeMgr        *tmux.PipePaneManager
-	wsSrv          *ws.Server
-	httpSrv        *http.Server
-	gtDir          string
-	port           int
-	authToken      string
-	originPatterns []string
+	ctrl        *tmux.ControlMode
+	registry    *agents.Registry
+	pipeMgr     *tmux.PipePaneManager
+	wsSrv       *ws.Server
+	restHandler *rest.Handler
+	httpSrv     *http.Server
+	unixLn      net.Listener
+	history     *history.Store
+	recorder    *history.Recorder
+	audit       *audit.Logger
+	cfg         Config
 }
 
 // New creates a new Adapter.
-func New(gtDir string, port int, authToken string, originPatterns []string) *Adapter {
-	return &Adapter{
-		gtDir:          gtDir,
-		port:           port,
-		authToken:      authToken,
-		originPatterns: originPatterns,
-	}
+func New(cfg Config) *Adapter {
+	return &Adapter{cfg: cfg}
 }
 
 // Start initializes all components and starts the HTTP/WebSocket server.
 func (a *Adapter) Start() error {
+	// 0. Load API tokens
+	var tokens []auth.Token
+	if a.cfg.AuthTokensFile != "" {
+		var err error
+		if tokens, err = auth.LoadTokensFile(a.cfg.AuthTokensFile); err != nil {
+			return err
+		}
+	}
+	var signer *auth.Signer
+	if a.cfg.SigningKeyFile != "" {
+		key, err := auth.LoadSigningKeyFile(a.cfg.SigningKeyFile)
+		if err != nil {
+			return err
+		}
+		if signer, err = auth.NewSigner(key); err != nil {
+			return fmt.Errorf("token signing key: %w", err)
+		}
+	}
+	authn, err := auth.NewAuthenticator(a.cfg.AuthToken, tokens, signer)
+	if err != nil {
+		return fmt.Errorf("auth tokens: %w", err)
+	}
+	if len(tokens) > 0 {
+		log.Printf("loaded %d API tokens from %s", len(tokens), a.cfg.AuthTokensFile)
+	}
+
+	// Load the TLS certificate (optional)
+	var tlsConfig *tls.Config
+	if a.cfg.TLSCert != "" || a.cfg.TLSKey != "" {
+		if a.cfg.TLSCert == "" || a.cfg.TLSKey == "" {
+			return fmt.Errorf("tls: both a certificate and a key are required")
+		}
+		reloader, err := certs.NewReloader(a.cfg.TLSCert, a.cfg.TLSKey)
+		if err != nil {
+			return err
+		}
+		var clientCAs *x509.CertPool
+		if a.cfg.TLSClientCA != "" {
+			if clientCAs, err = certs.LoadCertPool(a.cfg.TLSClientCA); err != nil {
+				return err
+			}
+		}
+		tlsConfig = certs.ServerConfig(reloader, clientCAs)
+	} else if a.cfg.TLSClientCA != "" {
+		return fmt.Errorf("tls: a client CA requires a certificate and key")
+	}
+
+	// Configure rate limits (optional)
+	var limits *ratelimit.Limiters
+	if a.cfg.RateLimit {
+		limitsCfg := ratelimit.DefaultConfig
+		if a.cfg.RateLimitsFile != "" {
+			if limitsCfg, err = ratelimit.LoadConfigFile(a.cfg.RateLimitsFile); err != nil {
+				return err
+			}
+		}
+		limits = ratelimit.New(limitsCfg)
+	}
+
+	// Open the audit log (optional)
+	if a.cfg.AuditLog != "" {
+		if a.audit, err = audit.Open(a.cfg.AuditLog, a.cfg.AuditMaxBytes, a.cfg.AuditMaxFiles); err != nil {
+			return err
+		}
+		log.Printf("auditing mutating actions to %s", a.cfg.AuditLog)
+	}
+
+	if err := a.cfg.Discovery.Validate(); err != nil {
+		return fmt.Errorf("ad-hoc session patterns: %w", err)
+	}
+
+	// Load restart policies (optional)
+	policies := lifecycle.DefaultRestartPolicies()
+	if a.cfg.RestartPoliciesFile != "" {
+		if policies, err = lifecycle.LoadRestartPoliciesFile(a.cfg.RestartPoliciesFile); err != nil {
+			return err
+		}
+	}
+
 	// 1. Connect to tmux in control mode
 	ctrl, err := tmux.NewControlMode()
 	if err != nil {
@@ -50,33 +198,85 @@ func (a *Adapter) Start() error {
 	a.ctrl = ctrl
 	log.Println("connected to tmux control mode")
 
-	// 2. Create agent registry
-	a.registry = agents.NewRegistry(ctrl, a.gtDir)
+	// 2. Create agent registry and the lifecycle manager that spawns agents
+	// and restarts them when they exit
+	a.registry = agents.NewRegistry(ctrl, a.cfg.GtDir, a.cfg.Discovery)
+	life := lifecycle.New(ctrl, a.registry, a.cfg.GtDir, policies)
+	a.registry.SetExitHandler(life.HandleExit)
+	a.registry.SetScanInterval(a.cfg.ScanInterval)
 
-	// 3. Create pipe-pane manager
-	a.pipeMgr = tmux.NewPipePaneManager(ctrl)
+	// 3. Create pipe-pane manager, streaming from each agent's own pane
+	a.pipeMgr = tmux.NewPipePaneManager(ctrl, a.cfg.FlushPolicy)
+	a.pipeMgr.SetTargetFunc(a.registry.Target)
 
 	// 4. Create WebSocket server
-	a.wsSrv = ws.NewServer(a.registry, a.pipeMgr, ctrl, a.authToken, a.originPatterns)
+	a.wsSrv = ws.NewServer(ws.Options{
+		Registry:         a.registry,
+		PipeMgr:          a.pipeMgr,
+		Ctrl:             ctrl,
+		Life:             life,
+		Authn:            authn,
+		OriginPatterns:   a.cfg.OriginPatterns,
+		Compression:      a.cfg.WSCompression,
+		Audit:            a.audit,
+		Limits:           limits,
+		InputLockTimeout: a.cfg.InputLockTimeout,
+		ViewersAttach:    a.cfg.WebViewersAttach,
+		ResizePolicy:     a.cfg.ResizePolicy,
+	})
+
+	// 5. Open the transcript store (optional)
+	if a.cfg.History {
+		dir := a.cfg.HistoryDir
+		if dir == "" {
+			dir = filepath.Join(a.cfg.GtDir, ".tmux-adapter", "history")
+		}
+		store, err := history.NewStore(dir, a.cfg.HistoryRetention)
+		if err != nil {
+			ctrl.Close()
+			return fmt.Errorf("history store: %w", err)
+		}
+		a.history = store
+		a.recorder = history.NewRecorder(store, a.pipeMgr)
+		a.recorder.Start(time.Hour)
+		log.Printf("recording agent transcripts to %s", dir)
+	}
+
+	// 6. Create REST handler (also serves Server-Sent Event streams)
+	var watched func(string) bool
+	if a.cfg.WebViewersAttach {
+		watched = a.wsSrv.Watched
+	}
+	a.restHandler = rest.New(rest.Options{
+		Registry:  a.registry,
+		Ctrl:      ctrl,
+		Life:      life,
+		PipeMgr:   a.pipeMgr,
+		Authn:     authn,
+		History:   a.history,
+		Audit:     a.audit,
+		Limits:    limits,
+		Watched:   watched,
+		InputLock: a.wsSrv.InputLockHolder,
+	})
 
-	// 5. Start registry watching
+	// 7. Start registry watching
 	if err := a.registry.Start(); err != nil {
 		ctrl.Close()
 		return fmt.Errorf("start registry: %w", err)
 	}
 	log.Printf("agent registry started (%d agents found)", len(a.registry.GetAgents()))
 
-	// 6. Forward registry events to WebSocket clients
+	// 8. Forward registry events to WebSocket and SSE clients
 	go a.forwardEvents()
 
-	// 7. Start HTTP server
+	// 9. Start HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", a.handleHealth)
 	mux.HandleFunc("/readyz", a.handleReady)
 	mux.Handle("/ws", a.wsSrv)
 
-	restHandler := rest.New(a.registry, a.ctrl, a.authToken)
-	restHandler.Register(mux)
+	a.restHandler.Register(mux)
 
 	// Serve embedded web component files at /tmux-adapter-web/
 	componentFS, _ := fs.Sub(web.ComponentFiles, "tmux-adapter-web")
@@ -85,18 +285,45 @@ func (a *Adapter) Start() error {
 	))
 
 	a.httpSrv = &http.Server{
-		Addr:    fmt.Sprintf(":%d", a.port),
-		Handler: mux,
+		Addr:        fmt.Sprintf(":%d", a.cfg.Port),
+		Handler:     mux,
+		TLSConfig:   tlsConfig,
+		ConnContext: peerConnContext,
 	}
+	if a.cfg.UnixSocket != "" {
+		if a.unixLn, err = listenUnix(a.cfg.UnixSocket, a.cfg.UnixSocketMode); err != nil {
+			ctrl.Close()
+			return err
+		}
+	}
+	// End long-lived SSE responses so Shutdown does not wait on them.
+	a.httpSrv.RegisterOnShutdown(a.restHandler.Close)
 
 	go func() {
-		log.Printf("WebSocket server listening on ws://localhost:%d/ws", a.port)
-		log.Printf("watching gastown at %s", a.gtDir)
-		if err := a.httpSrv.ListenAndServe(); err != http.ErrServerClosed {
+		scheme, serve := "ws", a.httpSrv.ListenAndServe
+		if tlsConfig != nil {
+			// Certificates come from TLSConfig.GetCertificate.
+			scheme, serve = "wss", func() error { return a.httpSrv.ListenAndServeTLS("", "") }
+		}
+		log.Printf("WebSocket server listening on %s://localhost:%d/ws", scheme, a.cfg.Port)
+		log.Printf("watching gastown at %s", a.cfg.GtDir)
+		if a.cfg.Discovery.Enabled() {
+			log.Printf("also tracking ad-hoc agent sessions")
+		}
+		if err := serve(); err != http.ErrServerClosed {
 			log.Fatalf("http server: %v", err)
 		}
 	}()
 
+	if a.unixLn != nil {
+		go func() {
+			log.Printf("WebSocket server listening on unix:%s", a.cfg.UnixSocket)
+			if err := a.httpSrv.Serve(a.unixLn); err != http.ErrServerClosed {
+				log.Fatalf("unix socket server: %v", err)
+			}
+		}()
+	}
+
 	return nil
 }
 
@@ -104,12 +331,15 @@ func (a *Adapter) Start() error {
 func (a *Adapter) Stop() {
 	log.Println("shutting down...")
 
-	// 1. Shutdown HTTP server
+	// 1. Shutdown HTTP server (and the Unix socket listener)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := a.httpSrv.Shutdown(ctx); err != nil {
 		log.Printf("http shutdown: %v", err)
 	}
+	if a.unixLn != nil {
+		os.Remove(a.cfg.UnixSocket)
+	}
 
 	// 2. Close all WebSocket connections
 	a.wsSrv.CloseAll()
@@ -117,21 +347,43 @@ func (a *Adapter) Stop() {
 	// 3. Stop registry
 	a.registry.Stop()
 
-	// 4. Stop all pipe-panes
+	// 4. Stop transcript recording (flushes pending lines)
+	if a.recorder != nil {
+		a.recorder.Stop()
+	}
+
+	// 5. Flush the audit log
+	if err := a.audit.Close(); err != nil {
+		log.Printf("audit log close: %v", err)
+	}
+
+	// 6. Stop all pipe-panes
 	a.pipeMgr.StopAll()
 
-	// 5. Close control mode (kills monitor session)
+	// 7. Close control mode (kills monitor session)
 	a.ctrl.Close()
 
 	log.Println("shutdown complete")
 }
 
 // forwardEvents reads agent lifecycle events from the registry and pushes them to
-// subscribed WebSocket clients.
+// subscribed WebSocket clients, SSE clients and the transcript recorder.
 func (a *Adapter) forwardEvents() {
 	for event := range a.registry.Events() {
-		msg := ws.MakeAgentEvent(event.Type, event.Agent)
-		a.wsSrv.BroadcastToAgentSubscribers(msg)
+		if a.recorder != nil {
+			switch event.Type {
+			case "added":
+				a.recorder.Track(event.Agent.Name)
+			case "removed":
+				a.recorder.Untrack(event.Agent.Name)
+			}
+		}
+		if event.Type == "removed" {
+			a.wsSrv.AgentRemoved(event.Agent.Name)
+		}
+		msg := ws.MakeAgentEvent(event)
+		a.wsSrv.BroadcastToAgentSubscribers(event.Agent, msg)
+		a.restHandler.PublishAgentEvent(event.Type, event.Agent, msg)
 	}
 }
 
@@ -157,6 +409,47 @@ func (a *Adapter) handleReady(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// listenUnix listens on a Unix socket, replacing a stale socket left by a
+// previous run.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s: already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale unix socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen unix socket: %w", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("chmod unix socket: %w", err)
+	}
+	return ln, nil
+}
+
+// peerConnContext attaches SO_PEERCRED credentials to Unix socket connections
+// so the authenticator can identify the calling user.
+func peerConnContext(ctx context.Context, c net.Conn) context.Context {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	cred, err := auth.ReadPeerCred(uc)
+	if err != nil {
+		log.Printf("unix socket peer credentials: %v", err)
+		return ctx
+	}
+	return auth.WithPeer(ctx, cred)
+}
+
 func corsHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -0,0 +1,213 @@
+package agents
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+// crashTailLines is how many lines of an agent's last output a Crash keeps.
+const crashTailLines = 50
+
+// maxCrashHistory bounds the crashes kept in memory.
+const maxCrashHistory = 500
+
+// Crash records an agent process that exited non-zero or was killed by a
+// signal.
+type Crash struct {
+	Agent    string    `json:"agent"`
+	PID      int       `json:"pid"`
+	ExitCode int       `json:"exitCode"`         // 128+signal when killed, as shells report it
+	Signal   int       `json:"signal,omitempty"` // terminating signal, if any
+	Output   []string  `json:"output"`           // last lines of the pane
+	Time     time.Time `json:"time"`
+}
+
+// crashHistory is a bounded, oldest-first log of crashes.
+type crashHistory struct {
+	mu      sync.Mutex
+	max     int
+	crashes []Crash
+}
+
+func (h *crashHistory) add(c Crash) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.crashes = append(h.crashes, c)
+	if len(h.crashes) > h.max {
+		h.crashes = h.crashes[len(h.crashes)-h.max:]
+	}
+}
+
+// list returns up to limit crashes, newest first, for which keep reports true.
+func (h *crashHistory) list(keep func(Crash) bool, limit int) []Crash {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := []Crash{}
+	for i := len(h.crashes) - 1; i >= 0 && len(out) < limit; i-- {
+		if keep(h.crashes[i]) {
+			out = append(out, h.crashes[i])
+		}
+	}
+	return out
+}
+
+// Crashes returns up to limit recorded crashes, newest first, for which keep
+// reports true.
+func (r *Registry) Crashes(keep func(Crash) bool, limit int) []Crash {
+	return r.crashes.list(keep, limit)
+}
+
+// watchCrashes installs the pane-died hook on a newly discovered agent's
+// session, or moves it to the agent's new pane. Called with scanMu held.
+func (r *Registry) watchCrashes(name, pane string) {
+	prev, ok := r.hooked[name]
+	if prev == pane {
+		return
+	}
+	if ok {
+		if err := r.ctrl.UnwatchPaneDied(name, prev); err != nil {
+			log.Printf("unwatch %s pane %s for crashes: %v", name, prev, err)
+		}
+	}
+	if err := r.ctrl.WatchPaneDied(name, pane); err != nil {
+		log.Printf("watch %s for crashes: %v", name, err)
+		delete(r.hooked, name)
+		return
+	}
+	r.hooked[name] = pane
+}
+
+// unwatchCrashes removes the pane-died hooks so agent sessions close normally
+// once the adapter is gone.
+func (r *Registry) unwatchCrashes() {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	for name, pane := range r.hooked {
+		if err := r.ctrl.UnwatchPaneDied(name, pane); err != nil {
+			log.Printf("unwatch %s for crashes: %v", name, err)
+		}
+		delete(r.hooked, name)
+	}
+}
+
+// ExitHandler decides what happens to an agent whose pane died. It returns
+// true when it takes over the dead pane (e.g. to respawn it after a backoff);
+// otherwise the pane is closed.
+type ExitHandler func(agent Agent, pane tmux.DeadPane) bool
+
+// SetExitHandler installs the handler consulted when an agent's pane dies.
+// Call it before Start.
+func (r *Registry) SetExitHandler(h ExitHandler) {
+	r.onExit = h
+}
+
+// sweepCrashes handles every dead agent pane left open by the pane-died hook.
+// Failures are recorded and emitted as "crashed"; then the exit handler may
+// take the pane over, or it is closed, which reports "removed" as if it had
+// closed on its own. Dead panes in windows where gastown turned remain-on-exit
+// on are only recorded; gastown's own hooks handle them. Several deaths may
+// share one notification, so tmux is asked for all dead panes rather than the
+// one that fired.
+func (r *Registry) sweepCrashes() {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+
+	panes, err := r.ctrl.DeadPanes()
+	if err != nil {
+		log.Printf("list dead panes: %v", err)
+		return
+	}
+	dead := make(map[string]bool, len(panes))
+	for _, pane := range panes {
+		dead[pane.PaneID] = true
+	}
+	for paneID := range r.exited {
+		if !dead[paneID] {
+			delete(r.exited, paneID) // respawned or closed
+		}
+	}
+
+	for _, pane := range panes {
+		_, hooked := r.hooked[pane.Session]
+		if (!IsGastownSession(pane.Session) && !hooked) || pane.Session == "adapter-monitor" || r.exited[pane.PaneID] == pane.PID {
+			continue
+		}
+
+		r.mu.RLock()
+		agent := r.knownAgent(pane.Session)
+		r.mu.RUnlock()
+		agentPane := agent.PaneID == "" || agent.PaneID == pane.PaneID
+		if agentPane {
+			r.exited[pane.PaneID] = pane.PID
+			if pane.Status != 0 {
+				r.recordCrash(agent, pane)
+			}
+		}
+		switch {
+		case !pane.Owned:
+			continue // gastown keeps this window's dead panes, e.g. to respawn them
+		case agentPane && r.onExit != nil && r.onExit(agent, pane):
+			r.ExpectRestart(pane.Session)
+			continue
+		}
+		// Otherwise the pane, or an auxiliary pane sharing the agent's
+		// window, is closed as it would have been without remain-on-exit.
+		delete(r.exited, pane.PaneID)
+		if err := r.ctrl.KillPane(pane.PaneID); err != nil {
+			log.Printf("close dead pane of %s: %v", pane.Session, err)
+		}
+	}
+}
+
+// recordCrash adds a failed pane to the crash history and emits "crashed".
+func (r *Registry) recordCrash(agent Agent, pane tmux.DeadPane) {
+	crash := Crash{
+		Agent:    pane.Session,
+		PID:      pane.PID,
+		ExitCode: pane.Status,
+		Signal:   pane.Signal,
+		Time:     pane.Time,
+	}
+	if pane.Signal != 0 {
+		crash.ExitCode = 128 + pane.Signal
+	}
+	if crash.Time.IsZero() {
+		crash.Time = time.Now()
+	}
+	var err error
+	if crash.Output, err = r.ctrl.CapturePaneTail(pane.PaneID, crashTailLines); err != nil {
+		log.Printf("capture output of crashed %s: %v", pane.Session, err)
+	}
+	log.Printf("agent %s crashed: pid %d exited with %d: %s", crash.Agent, crash.PID, crash.ExitCode, lastLine(crash.Output))
+
+	r.crashes.add(crash)
+	r.events.push(RegistryEvent{Type: "crashed", Agent: agent, Crash: &crash})
+}
+
+// knownAgent returns the last known state of a (possibly departed) agent.
+// Called with mu held.
+func (r *Registry) knownAgent(name string) Agent {
+	if agent, ok := r.agents[name]; ok {
+		return agent
+	}
+	if pending, ok := r.restarting[name]; ok {
+		return pending.agent
+	}
+	role, rig := ParseSessionName(name)
+	agent := Agent{Name: name, Role: role}
+	if rig != "" {
+		agent.Rig = &rig
+	}
+	return agent
+}
+
+func lastLine(lines []string) string {
+	if len(lines) == 0 {
+		return "(no output)"
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
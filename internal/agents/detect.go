@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
 )
 
 // Agent represents a live AI coding agent running in gastown.
@@ -15,6 +17,35 @@ type Agent struct {
 	Rig      *string `json:"rig"`
 	WorkDir  string  `json:"workDir"`
 	Attached bool    `json:"attached"`
+	PaneID   string  `json:"paneId"`             // the pane running the agent process
+	AuxPanes []Pane  `json:"auxPanes,omitempty"` // the session's other panes, e.g. a human's test shell
+}
+
+// Pane is an auxiliary pane in an agent's session. Clients may watch its
+// output but not type into it.
+type Pane struct {
+	ID      string `json:"id"`
+	Window  int    `json:"window"`
+	Command string `json:"command"`
+}
+
+// Target returns the tmux target for the agent's pane: its pane ID, or the
+// session name when the pane is not known.
+func (a Agent) Target() string {
+	if a.PaneID != "" {
+		return a.PaneID
+	}
+	return a.Name
+}
+
+// AuxPane looks up one of the agent's auxiliary panes.
+func (a Agent) AuxPane(id string) (Pane, bool) {
+	for _, p := range a.AuxPanes {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Pane{}, false
 }
 
 // runtimeProcessNames maps agent preset names to the process names they run as.
@@ -53,6 +84,42 @@ func IsShell(command string) bool {
 	return knownShells[command]
 }
 
+// IsAgentPane reports whether a pane is running one of processNames.
+// Detection priority (from gastown spec):
+// 1. Direct pane command match
+// 2. Shell wrapping agent → check descendants
+// 3. Unrecognized command (version-as-argv[0]) → check binary, then descendants
+func IsAgentPane(pane tmux.PaneInfo, processNames []string) bool {
+	switch {
+	case IsAgentProcess(pane.Command, processNames):
+		return true
+	case pane.PID == "":
+		return false
+	case IsShell(pane.Command):
+		return CheckDescendants(pane.PID, processNames)
+	default:
+		// Unrecognized pane command (e.g., "2.1.38" for Claude Code)
+		// Check the actual binary path, then descendants
+		return CheckProcessBinary(pane.PID, processNames) || CheckDescendants(pane.PID, processNames)
+	}
+}
+
+// FindAgentPane returns the pane running the agent among a session's panes,
+// like gastown's FindAgentPane. The pane prev, where the agent was last seen,
+// wins if it still runs it; otherwise the first matching pane in window
+// order does.
+func FindAgentPane(panes []tmux.PaneInfo, processNames []string, prev string) (tmux.PaneInfo, bool) {
+	if i := slices.IndexFunc(panes, func(p tmux.PaneInfo) bool { return p.PaneID == prev }); i >= 0 && IsAgentPane(panes[i], processNames) {
+		return panes[i], true
+	}
+	for _, pane := range panes {
+		if pane.PaneID != prev && IsAgentPane(pane, processNames) {
+			return pane, true
+		}
+	}
+	return tmux.PaneInfo{}, false
+}
+
 // CheckProcessBinary checks the actual binary path of a process (via ps -o comm=)
 // against the expected process names. Handles the version-as-argv[0] case where
 // Claude Code shows "2.1.38" as the pane command but the actual binary is "claude".
@@ -104,7 +171,8 @@ func checkDescendantsDepth(pid string, processNames []string, depth int) bool {
 }
 
 // ParseSessionName extracts role and rig from a gastown session name.
-// Returns role and rig (empty string for town-level agents).
+// Returns role and rig (empty string for town-level agents); names outside
+// gastown's conventions are ad-hoc agents.
 func ParseSessionName(name string) (role string, rig string) {
 	// Town-level: hq-ROLE
 	if rest, ok := strings.CutPrefix(name, "hq-"); ok {
@@ -148,7 +216,7 @@ func ParseSessionName(name string) (role string, rig string) {
 		return "polecat", rigName
 	}
 
-	return "unknown", ""
+	return RoleAdhoc, ""
 }
 
 // InferRuntime tries to determine the agent runtime from the pane command or binary.
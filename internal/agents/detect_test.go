@@ -0,0 +1,38 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+func TestFindAgentPane(t *testing.T) {
+	names := GetProcessNames("claude")
+	// Panes without a PID are never walked for descendants, so only their
+	// command decides.
+	human := tmux.PaneInfo{PaneID: "%1", Command: "vim"}
+	agent := tmux.PaneInfo{PaneID: "%2", Command: "claude"}
+	other := tmux.PaneInfo{PaneID: "%3", Command: "node"}
+	exited := tmux.PaneInfo{PaneID: "%2", Command: "bash"}
+
+	tests := []struct {
+		name   string
+		panes  []tmux.PaneInfo
+		prev   string
+		want   string
+		wantOK bool
+	}{
+		{"skips a human's split", []tmux.PaneInfo{human, agent}, "", "%2", true},
+		{"prefers the previous pane", []tmux.PaneInfo{other, agent}, "%2", "%2", true},
+		{"first match without history", []tmux.PaneInfo{other, agent}, "", "%3", true},
+		{"moves on when the previous pane exited", []tmux.PaneInfo{exited, other}, "%2", "%3", true},
+		{"previous pane gone", []tmux.PaneInfo{human, other}, "%9", "%3", true},
+		{"no agent", []tmux.PaneInfo{human, exited}, "%2", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FindAgentPane(tt.panes, names, tt.prev)
+		if ok != tt.wantOK || got.PaneID != tt.want {
+			t.Errorf("%s: FindAgentPane() = %q, %v; want %q, %v", tt.name, got.PaneID, ok, tt.want, tt.wantOK)
+		}
+	}
+}
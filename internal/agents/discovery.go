@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"log"
+	"path"
+	"slices"
+	"sort"
+)
+
+// RoleAdhoc is the role of agents found in sessions outside gastown's hq-/gt-
+// naming convention.
+const RoleAdhoc = "adhoc"
+
+// DiscoveryRules admit sessions outside gastown's naming convention as ad-hoc
+// agents, e.g. a developer's own claude session. A session matching any rule
+// is an agent while one of its panes runs a known runtime. With no rules set
+// (the default) only gastown sessions are tracked.
+type DiscoveryRules struct {
+	Sessions   []string // session name globs (path.Match syntax)
+	EnvMarkers []string // session environment variables whose presence marks an agent
+	AnyRuntime bool     // every session with a pane running a known runtime
+}
+
+// Enabled reports whether any ad-hoc rule is set.
+func (d DiscoveryRules) Enabled() bool {
+	return len(d.Sessions) > 0 || len(d.EnvMarkers) > 0 || d.AnyRuntime
+}
+
+// matchName reports whether a session name matches one of the globs.
+func (d DiscoveryRules) matchName(name string) bool {
+	for _, pattern := range d.Sessions {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the session globs.
+func (d DiscoveryRules) Validate() error {
+	for _, pattern := range d.Sessions {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genericProcessNames run plenty besides agents, so they do not mark an
+// ad-hoc session as an agent on their own.
+var genericProcessNames = []string{"node", "bun"}
+
+// adhocProcessNames returns the process names that identify an agent in an
+// ad-hoc session: every runtime's, except generic interpreters.
+func adhocProcessNames() []string {
+	var names []string
+	for _, runtimeNames := range runtimeProcessNames {
+		for _, name := range runtimeNames {
+			if !slices.Contains(genericProcessNames, name) && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// admit reports whether a non-gastown session matches the rules. env reads
+// the session's environment; it is only called for env markers.
+func (d DiscoveryRules) admit(session string, env func() (map[string]string, error)) bool {
+	if d.AnyRuntime || d.matchName(session) {
+		return true
+	}
+	if len(d.EnvMarkers) == 0 {
+		return false
+	}
+	vars, err := env()
+	if err != nil {
+		log.Printf("environment of %s: %v", session, err)
+		return false
+	}
+	for _, key := range d.EnvMarkers {
+		if _, ok := vars[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// admitAdhoc reports whether a non-gastown session matches the discovery
+// rules.
+func (r *Registry) admitAdhoc(session string) bool {
+	return r.rules.admit(session, func() (map[string]string, error) {
+		return r.ctrl.ShowEnvironmentAll(session)
+	})
+}
@@ -0,0 +1,77 @@
+package agents
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDiscoveryRulesAdmit(t *testing.T) {
+	envs := map[string]map[string]string{
+		"scratch": {"MY_AGENT": "1"},
+		"notes":   {"EDITOR": "vim"},
+	}
+	env := func(session string) func() (map[string]string, error) {
+		return func() (map[string]string, error) {
+			if vars, ok := envs[session]; ok {
+				return vars, nil
+			}
+			return nil, errors.New("no such session")
+		}
+	}
+
+	tests := []struct {
+		name    string
+		rules   DiscoveryRules
+		session string
+		want    bool
+	}{
+		{"no rules", DiscoveryRules{}, "claude-1", false},
+		{"name glob", DiscoveryRules{Sessions: []string{"dev-*", "claude-*"}}, "claude-1", true},
+		{"name glob miss", DiscoveryRules{Sessions: []string{"claude-*"}}, "notes", false},
+		{"env marker", DiscoveryRules{EnvMarkers: []string{"MY_AGENT"}}, "scratch", true},
+		{"env marker absent", DiscoveryRules{EnvMarkers: []string{"MY_AGENT"}}, "notes", false},
+		{"env unreadable", DiscoveryRules{EnvMarkers: []string{"MY_AGENT"}}, "gone", false},
+		{"any runtime", DiscoveryRules{AnyRuntime: true}, "notes", true},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.admit(tt.session, env(tt.session)); got != tt.want {
+			t.Errorf("%s: admit(%s) = %v, want %v", tt.name, tt.session, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoveryRulesReadEnvOnlyForMarkers(t *testing.T) {
+	env := func() (map[string]string, error) {
+		t.Fatal("environment read without env markers")
+		return nil, nil
+	}
+	DiscoveryRules{Sessions: []string{"claude-*"}}.admit("notes", env)
+	DiscoveryRules{AnyRuntime: true, EnvMarkers: []string{"MY_AGENT"}}.admit("notes", env)
+}
+
+func TestDiscoveryRulesValidate(t *testing.T) {
+	if err := (DiscoveryRules{Sessions: []string{"claude-*"}}).Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if err := (DiscoveryRules{Sessions: []string{"claude-["}}).Validate(); err == nil {
+		t.Fatal("Validate() accepted a malformed glob")
+	}
+}
+
+func TestAdhocProcessNamesExcludeInterpreters(t *testing.T) {
+	names := adhocProcessNames()
+	for _, generic := range genericProcessNames {
+		if slices.Contains(names, generic) {
+			t.Errorf("adhocProcessNames() contains %s", generic)
+		}
+	}
+	for _, want := range []string{"claude", "codex", "gemini"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("adhocProcessNames() = %v, missing %s", names, want)
+		}
+	}
+	if !slices.IsSorted(names) || len(slices.Compact(slices.Clone(names))) != len(names) {
+		t.Errorf("adhocProcessNames() = %v, want sorted and unique", names)
+	}
+}
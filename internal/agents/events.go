@@ -0,0 +1,108 @@
+package agents
+
+import "sync"
+
+// eventQueue carries registry events from scans to the consumer of Events.
+// Pushing never blocks, so a stalled consumer cannot hold up a scan. Events
+// still waiting for delivery are coalesced per agent, which also bounds the
+// queue.
+type eventQueue struct {
+	mu      sync.Mutex
+	pending []RegistryEvent
+	wake    chan struct{}
+	out     chan RegistryEvent
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{
+		wake: make(chan struct{}, 1),
+		out:  make(chan RegistryEvent),
+	}
+}
+
+// push queues events for delivery, merging each with the agent's last
+// undelivered event where possible.
+func (q *eventQueue) push(events ...RegistryEvent) {
+	if len(events) == 0 {
+		return
+	}
+	q.mu.Lock()
+	for _, e := range events {
+		q.pending = coalesceInto(q.pending, e)
+	}
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events in order until stop closes.
+func (q *eventQueue) run(stop <-chan struct{}) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-q.wake:
+				continue
+			case <-stop:
+				return
+			}
+		}
+		e := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		select {
+		case q.out <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// len returns the number of undelivered events.
+func (q *eventQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// coalesceInto appends e to pending, or merges it with the last pending event
+// for the same agent. A client that has not seen the earlier event yet needs
+// only the outcome: an agent added and removed again is never reported, a
+// removal followed by a return is a restart, and only the latest state of an
+// update is kept. Crashes are always delivered.
+func coalesceInto(pending []RegistryEvent, e RegistryEvent) []RegistryEvent {
+	i := len(pending) - 1
+	for i >= 0 && pending[i].Agent.Name != e.Agent.Name {
+		i--
+	}
+	if i < 0 || e.Type == "crashed" || pending[i].Type == "crashed" {
+		return append(pending, e)
+	}
+
+	prev := &pending[i]
+	switch {
+	case prev.Type == "added" && e.Type == "removed":
+		return append(pending[:i], pending[i+1:]...)
+	case prev.Type == "removed" && e.Type == "added":
+		if prev.PrevPID == e.PID {
+			*prev = RegistryEvent{Type: "updated", Agent: e.Agent}
+		} else {
+			*prev = RegistryEvent{Type: "restarted", Agent: e.Agent, PrevPID: prev.PrevPID, PID: e.PID}
+		}
+	case prev.Type == "restarted" && e.Type == "restarted":
+		prev.Agent, prev.PID = e.Agent, e.PID
+	case prev.Type != "removed" && e.Type == "updated":
+		prev.Agent = e.Agent // added, updated or restarted: keep the type
+	case prev.Type == "added" && e.Type == "restarted":
+		prev.Agent, prev.PID = e.Agent, e.PID // the old process was never reported
+	case prev.Type != "removed" && e.Type == "removed":
+		*prev = e
+	default:
+		return append(pending, e)
+	}
+	return pending
+}
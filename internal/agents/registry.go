@@ -2,59 +2,110 @@ package agents
 
 import (
 	"log"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gastownhall/tmux-adapter/internal/tmux"
 )
 
+// restartWindow is how long an agent being respawned may be missing before
+// it is reported removed rather than restarted.
+const restartWindow = 30 * time.Second
+
+// DefaultScanInterval is how often the registry rescans tmux even without
+// notifications, to catch agents that exit while their session stays.
+const DefaultScanInterval = 5 * time.Second
+
 // RegistryEvent represents a change in agent state.
 type RegistryEvent struct {
-	Type  string // "added", "removed", "updated"
-	Agent Agent
+	Type    string // "added", "removed", "updated" (attached or panes), "restarted", "crashed"
+	Agent   Agent
+	PrevPID int    // restarted: the replaced pane process; removed: the last one
+	PID     int    // added, restarted: the (new) pane process
+	Crash   *Crash // crashed: exit status and last output
 }
 
 // Registry tracks live agents and emits lifecycle events.
 type Registry struct {
-	ctrl   *tmux.ControlMode
-	mu     sync.RWMutex
-	agents map[string]Agent // name -> agent
-	events chan RegistryEvent
-	gtDir  string
-	stopCh chan struct{}
+	ctrl       *tmux.ControlMode
+	rules      DiscoveryRules
+	scanMu     sync.Mutex // serializes scans so their diffs apply in order
+	mu         sync.RWMutex
+	agents     map[string]Agent // name -> agent
+	pids       map[string]int   // name -> pane process
+	restarting map[string]pendingRestart
+	expected   map[string]time.Time // name -> when a respawn was announced
+	hooked     map[string]string    // session -> agent pane with the pane-died hook; guarded by scanMu
+	exited     map[string]int       // dead pane ID -> process, taken over by onExit; guarded by scanMu
+	onExit     ExitHandler
+	crashes    *crashHistory
+	events     *eventQueue
+	gtDir      string
+	interval   time.Duration // periodic scan; 0 scans on notifications only
+	stopCh     chan struct{}
+}
+
+// pendingRestart is an agent whose process went away while a respawn of its
+// pane was in flight.
+type pendingRestart struct {
+	agent Agent
+	pid   int
+	since time.Time
 }
 
-// NewRegistry creates a new agent registry.
-func NewRegistry(ctrl *tmux.ControlMode, gtDir string) *Registry {
+// NewRegistry creates a new agent registry. rules admit ad-hoc agents
+// outside gastown's session naming; the zero value tracks gastown only.
+func NewRegistry(ctrl *tmux.ControlMode, gtDir string, rules DiscoveryRules) *Registry {
 	return &Registry{
-		ctrl:   ctrl,
-		agents: make(map[string]Agent),
-		events: make(chan RegistryEvent, 100),
-		gtDir:  gtDir,
-		stopCh: make(chan struct{}),
+		ctrl:       ctrl,
+		rules:      rules,
+		agents:     make(map[string]Agent),
+		pids:       make(map[string]int),
+		restarting: make(map[string]pendingRestart),
+		expected:   make(map[string]time.Time),
+		hooked:     make(map[string]string),
+		exited:     make(map[string]int),
+		crashes:    &crashHistory{max: maxCrashHistory},
+		events:     newEventQueue(),
+		gtDir:      gtDir,
+		interval:   DefaultScanInterval,
+		stopCh:     make(chan struct{}),
 	}
 }
 
 // Start begins watching for agent changes.
 func (r *Registry) Start() error {
+	go r.events.run(r.stopCh)
+
 	// Initial scan
 	if err := r.scan(); err != nil {
 		return err
 	}
 
+	// Learn about crashes from the pane-died hooks, and record any agent
+	// that died while no adapter was watching.
+	if err := r.ctrl.SubscribePaneDied(); err != nil {
+		log.Printf("subscribe to pane-died hooks: %v", err)
+	}
+	r.sweepCrashes()
+
 	// Watch for tmux notifications
 	go r.watchLoop()
 	return nil
 }
 
-// Stop halts the registry watcher.
+// Stop halts the registry watcher and removes its pane-died hooks.
 func (r *Registry) Stop() {
 	close(r.stopCh)
+	r.unwatchCrashes()
 }
 
 // Events returns the channel for receiving lifecycle events.
 func (r *Registry) Events() <-chan RegistryEvent {
-	return r.events
+	return r.events.out
 }
 
 // GetAgents returns a snapshot of all currently known agents.
@@ -77,22 +128,177 @@ func (r *Registry) GetAgent(name string) (Agent, bool) {
 	return a, ok
 }
 
+// Target returns the tmux target for an agent's commands: the ID of the
+// pane running it, or the name itself for unknown agents and pane IDs.
+func (r *Registry) Target(name string) string {
+	if agent, ok := r.GetAgent(name); ok {
+		return agent.Target()
+	}
+	return name
+}
+
+// paneID returns the agent pane last seen in a session, if any.
+func (r *Registry) paneID(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if agent, ok := r.agents[name]; ok {
+		return agent.PaneID
+	}
+	return r.restarting[name].agent.PaneID
+}
+
+// PanePID returns the pane process of a live agent.
+func (r *Registry) PanePID(name string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pid, ok := r.pids[name]
+	return pid, ok
+}
+
+// ExpectRestart announces that name's pane is about to be respawned. A scan
+// that finds the agent gone in the meantime holds its removal for
+// restartWindow, so the new process is reported as "restarted". Without it a
+// vanished agent, e.g. one that exited back to the shell, is removed at once.
+func (r *Registry) ExpectRestart(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.expected[name] = now
+	if pending, ok := r.restarting[name]; ok {
+		// Still waiting, e.g. through a supervisor's backoff: restart the
+		// window for the respawn itself.
+		pending.since = now
+		r.restarting[name] = pending
+		time.AfterFunc(restartWindow, r.rescan)
+	}
+}
+
+// Refresh rescans tmux immediately, e.g. while waiting for a spawned agent's
+// process to start.
+func (r *Registry) Refresh() error {
+	return r.scan()
+}
+
+// SetScanInterval sets how often tmux is rescanned besides on notifications;
+// 0 disables the periodic scan. Call it before Start.
+func (r *Registry) SetScanInterval(d time.Duration) {
+	r.interval = max(d, 0)
+}
+
+// rescan runs a scan outside the notification loop, unless stopped.
+func (r *Registry) rescan() {
+	select {
+	case <-r.stopCh:
+		return
+	default:
+	}
+	if err := r.scan(); err != nil {
+		log.Printf("agent scan error: %v", err)
+	}
+}
+
+// rescanNotifications hint that an agent may have started or exited while
+// its session stayed, e.g. a window closing, or renamed as its pane drops
+// back to the shell.
+var rescanNotifications = map[string]bool{
+	"window-add":              true,
+	"window-close":            true,
+	"window-renamed":          true,
+	"window-pane-changed":     true,
+	"unlinked-window-add":     true,
+	"unlinked-window-close":   true,
+	"unlinked-window-renamed": true,
+	"pane-mode-changed":       true,
+}
+
 func (r *Registry) watchLoop() {
+	pendingScan := debouncer{quiet: scanQuiet, maxDelay: scanMaxDelay}
+	var sweep <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
 	for {
 		select {
 		case <-r.stopCh:
+			pendingScan.stop()
 			return
+		case <-sweep:
+			// An agent can exit without any notification, and a pane-died
+			// notification can be lost; check both periodically.
+			pendingScan.trigger()
+			r.sweepCrashes()
 		case notif := <-r.ctrl.Notifications():
-			if notif.Type == "sessions-changed" {
-				if err := r.scan(); err != nil {
-					log.Printf("agent scan error: %v", err)
-				}
+			switch {
+			case notif.Type == "sessions-changed" || rescanNotifications[notif.Type]:
+				pendingScan.trigger()
+			case notif.Type == "subscription-changed" && strings.HasPrefix(notif.Args, tmux.PaneDiedSubscription+" "):
+				r.sweepCrashes()
+			}
+		case <-pendingScan.C():
+			pendingScan.fired()
+			if err := r.scan(); err != nil {
+				log.Printf("agent scan error: %v", err)
 			}
 		}
 	}
 }
 
+// Bursts of notifications, e.g. a script starting ten agents, are merged
+// into one scan: it runs once they pause for scanQuiet, and at the latest
+// scanMaxDelay after the first.
+const (
+	scanQuiet    = 50 * time.Millisecond
+	scanMaxDelay = 500 * time.Millisecond
+)
+
+// debouncer schedules one action for a burst of triggers.
+type debouncer struct {
+	quiet    time.Duration
+	maxDelay time.Duration
+	timer    *time.Timer
+	first    time.Time // first trigger of the pending burst; zero when idle
+}
+
+// trigger (re)schedules the action quiet from now, but no later than maxDelay
+// after the burst's first trigger.
+func (d *debouncer) trigger() {
+	now := time.Now()
+	if d.first.IsZero() {
+		d.first = now
+	}
+	delay := min(d.quiet, d.first.Add(d.maxDelay).Sub(now))
+	if d.timer == nil {
+		d.timer = time.NewTimer(max(delay, 0))
+	} else {
+		d.timer.Reset(max(delay, 0))
+	}
+}
+
+// C fires when the action is due; it is nil while nothing is pending.
+func (d *debouncer) C() <-chan time.Time {
+	if d.first.IsZero() {
+		return nil
+	}
+	return d.timer.C
+}
+
+// fired marks the pending action as run.
+func (d *debouncer) fired() {
+	d.first = time.Time{}
+}
+
+func (d *debouncer) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
 func (r *Registry) scan() error {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+
 	sessions, err := r.ctrl.ListSessions()
 	if err != nil {
 		return err
@@ -100,21 +306,27 @@ func (r *Registry) scan() error {
 
 	// Build new agent map from current tmux state
 	discovered := make(map[string]Agent)
+	discoveredPIDs := make(map[string]int)
+	present := make(map[string]bool, len(sessions))
 
 	for _, sess := range sessions {
-		if !IsGastownSession(sess.Name) {
-			continue
-		}
+		present[sess.Name] = true
 
 		// Skip the adapter's own monitor session
 		if sess.Name == "adapter-monitor" {
 			continue
 		}
 
-		// Get pane info for process detection and workDir
-		pane, err := r.ctrl.GetPaneInfo(sess.Name)
+		// Sessions outside gastown are agents only by the discovery rules
+		adhoc := !IsGastownSession(sess.Name)
+		if adhoc && (!r.rules.Enabled() || !r.admitAdhoc(sess.Name)) {
+			continue
+		}
+
+		// List every pane; the agent may share its session with others
+		panes, err := r.ctrl.ListPanes(sess.Name)
 		if err != nil {
-			log.Printf("pane info for %s: %v", sess.Name, err)
+			log.Printf("list panes for %s: %v", sess.Name, err)
 			continue
 		}
 
@@ -123,41 +335,30 @@ func (r *Registry) scan() error {
 		agentRole, _ := r.ctrl.ShowEnvironment(sess.Name, "GT_ROLE")
 		agentRig, _ := r.ctrl.ShowEnvironment(sess.Name, "GT_RIG")
 
-		// Determine process names to check
+		// Find the pane running the agent (not a zombie, not a human's
+		// split or second window)
 		processNames := GetProcessNames(agentName)
-
-		// Check if agent is alive (not a zombie)
-		// Detection priority (from gastown spec):
-		// 1. Direct pane command match
-		// 2. Shell wrapping agent → check descendants
-		// 3. Unrecognized command (version-as-argv[0]) → check binary, then descendants
-		alive := false
-		if IsAgentProcess(pane.Command, processNames) {
-			alive = true
-		} else if IsShell(pane.Command) && pane.PID != "" {
-			alive = CheckDescendants(pane.PID, processNames)
-		} else if pane.PID != "" {
-			// Unrecognized pane command (e.g., "2.1.38" for Claude Code)
-			// Check the actual binary path, then descendants
-			alive = CheckProcessBinary(pane.PID, processNames) || CheckDescendants(pane.PID, processNames)
+		if adhoc && agentName == "" {
+			processNames = adhocProcessNames()
 		}
-
+		pane, alive := FindAgentPane(panes, processNames, r.paneID(sess.Name))
 		if !alive {
 			continue
 		}
 
-		// Validate workDir against gtDir if set
-		if r.gtDir != "" && !strings.HasPrefix(pane.WorkDir, r.gtDir) {
+		// Validate workDir against gtDir if set; ad-hoc agents run anywhere
+		if !adhoc && r.gtDir != "" && !strings.HasPrefix(pane.WorkDir, r.gtDir) {
 			// This session's working directory doesn't belong to our gastown instance
 			continue
 		}
 
-		// Determine role and rig from session name (env vars override if available)
+		// Determine role and rig from session name (env vars override if
+		// available, except for ad-hoc agents)
 		role, rig := ParseSessionName(sess.Name)
-		if agentRole != "" {
+		if agentRole != "" && !adhoc {
 			role = agentRole
 		}
-		if agentRig != "" {
+		if agentRig != "" && !adhoc {
 			rig = agentRig
 		}
 
@@ -172,6 +373,19 @@ func (r *Registry) scan() error {
 			rigPtr = &rig
 		}
 
+		var aux []Pane
+		for _, p := range panes {
+			if p.PaneID != pane.PaneID {
+				aux = append(aux, Pane{ID: p.PaneID, Window: p.Window, Command: p.Command})
+			}
+		}
+
+		// Ad-hoc sessions belong to someone else: hooking them would keep
+		// their dead panes open and let the adapter close or respawn them.
+		if !adhoc {
+			r.watchCrashes(sess.Name, pane.PaneID)
+		}
+
 		discovered[sess.Name] = Agent{
 			Name:     sess.Name,
 			Role:     role,
@@ -179,32 +393,90 @@ func (r *Registry) scan() error {
 			Rig:      rigPtr,
 			WorkDir:  pane.WorkDir,
 			Attached: sess.Attached,
+			PaneID:   pane.PaneID,
+			AuxPanes: aux,
+		}
+		discoveredPIDs[sess.Name], _ = strconv.Atoi(pane.PID)
+	}
+
+	for name := range r.hooked {
+		if !present[name] {
+			delete(r.hooked, name)
 		}
 	}
 
-	// Diff against known agents
+	r.update(discovered, discoveredPIDs, present)
+	return nil
+}
+
+// update applies a scan's agents and emits the resulting events. Events are
+// queued after the lock is released, so lookups never wait on a consumer.
+func (r *Registry) update(discovered map[string]Agent, discoveredPIDs map[string]int, present map[string]bool) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	events := r.diff(discovered, discoveredPIDs, present)
+	r.mu.Unlock()
+	r.events.push(events...)
+}
 
-	// Find removed agents
+// diff updates the known agents to a scan's and returns the events that
+// describe the change. Called with mu held.
+func (r *Registry) diff(discovered map[string]Agent, discoveredPIDs map[string]int, present map[string]bool) []RegistryEvent {
+	var events []RegistryEvent
+	now := time.Now()
+
+	for name, since := range r.expected {
+		if now.Sub(since) >= restartWindow {
+			delete(r.expected, name)
+		}
+	}
+
+	// Find removed agents. One whose pane is being respawned keeps its
+	// removal on hold for restartWindow.
 	for name, oldAgent := range r.agents {
-		if _, exists := discovered[name]; !exists {
-			delete(r.agents, name)
-			r.events <- RegistryEvent{Type: "removed", Agent: oldAgent}
+		if _, exists := discovered[name]; exists {
+			continue
+		}
+		delete(r.agents, name)
+		if _, expected := r.expected[name]; expected && present[name] {
+			delete(r.expected, name)
+			r.restarting[name] = pendingRestart{agent: oldAgent, pid: r.pids[name], since: now}
+			time.AfterFunc(restartWindow, r.rescan)
+		} else {
+			events = append(events, RegistryEvent{Type: "removed", Agent: oldAgent, PrevPID: r.pids[name]})
+		}
+		delete(r.pids, name)
+	}
+	for name, pending := range r.restarting {
+		if _, back := discovered[name]; back {
+			continue
+		}
+		if !present[name] || now.Sub(pending.since) >= restartWindow {
+			delete(r.restarting, name)
+			events = append(events, RegistryEvent{Type: "removed", Agent: pending.agent, PrevPID: pending.pid})
 		}
 	}
 
-	// Find added and updated agents
+	// Find added, restarted and updated agents
 	for name, newAgent := range discovered {
+		newPID := discoveredPIDs[name]
 		oldAgent, existed := r.agents[name]
-		if !existed {
-			r.agents[name] = newAgent
-			r.events <- RegistryEvent{Type: "added", Agent: newAgent}
-		} else if oldAgent.Attached != newAgent.Attached {
-			r.agents[name] = newAgent
-			r.events <- RegistryEvent{Type: "updated", Agent: newAgent}
+		switch {
+		case !existed:
+			if pending, ok := r.restarting[name]; ok {
+				delete(r.restarting, name)
+				events = append(events, RegistryEvent{Type: "restarted", Agent: newAgent, PrevPID: pending.pid, PID: newPID})
+			} else {
+				events = append(events, RegistryEvent{Type: "added", Agent: newAgent, PID: newPID})
+			}
+		case r.pids[name] != newPID:
+			delete(r.expected, name) // replaced before a scan saw it gone
+			events = append(events, RegistryEvent{Type: "restarted", Agent: newAgent, PrevPID: r.pids[name], PID: newPID})
+		case oldAgent.Attached != newAgent.Attached, oldAgent.PaneID != newAgent.PaneID, !slices.Equal(oldAgent.AuxPanes, newAgent.AuxPanes):
+			events = append(events, RegistryEvent{Type: "updated", Agent: newAgent})
 		}
+		r.agents[name] = newAgent
+		r.pids[name] = newPID
 	}
 
-	return nil
+	return events
 }
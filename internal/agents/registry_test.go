@@ -0,0 +1,280 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoalesceInto(t *testing.T) {
+	bob := Agent{Name: "gt-myrig-crew-bob"}
+	attached := Agent{Name: bob.Name, Attached: true}
+	mayor := Agent{Name: "hq-mayor"}
+	crash := &Crash{Agent: bob.Name}
+
+	tests := []struct {
+		name   string
+		events []RegistryEvent
+		want   []RegistryEvent
+	}{
+		{
+			name:   "added then removed is never reported",
+			events: []RegistryEvent{{Type: "added", Agent: bob, PID: 10}, {Type: "updated", Agent: mayor}, {Type: "removed", Agent: bob, PrevPID: 10}},
+			want:   []RegistryEvent{{Type: "updated", Agent: mayor}},
+		},
+		{
+			name:   "removed then added is a restart",
+			events: []RegistryEvent{{Type: "removed", Agent: bob, PrevPID: 10}, {Type: "added", Agent: attached, PID: 11}},
+			want:   []RegistryEvent{{Type: "restarted", Agent: attached, PrevPID: 10, PID: 11}},
+		},
+		{
+			name:   "removed then added with the same process is an update",
+			events: []RegistryEvent{{Type: "removed", Agent: bob, PrevPID: 10}, {Type: "added", Agent: attached, PID: 10}},
+			want:   []RegistryEvent{{Type: "updated", Agent: attached}},
+		},
+		{
+			name:   "updates keep the latest state",
+			events: []RegistryEvent{{Type: "added", Agent: bob, PID: 10}, {Type: "updated", Agent: attached}, {Type: "updated", Agent: bob}},
+			want:   []RegistryEvent{{Type: "added", Agent: bob, PID: 10}},
+		},
+		{
+			name:   "restarts chain from the first process",
+			events: []RegistryEvent{{Type: "restarted", Agent: bob, PrevPID: 10, PID: 11}, {Type: "restarted", Agent: attached, PrevPID: 11, PID: 12}},
+			want:   []RegistryEvent{{Type: "restarted", Agent: attached, PrevPID: 10, PID: 12}},
+		},
+		{
+			name:   "an update before removal is dropped",
+			events: []RegistryEvent{{Type: "updated", Agent: attached}, {Type: "removed", Agent: bob, PrevPID: 10}},
+			want:   []RegistryEvent{{Type: "removed", Agent: bob, PrevPID: 10}},
+		},
+		{
+			name:   "crashes are kept",
+			events: []RegistryEvent{{Type: "added", Agent: bob, PID: 10}, {Type: "crashed", Agent: bob, Crash: crash}, {Type: "removed", Agent: bob, PrevPID: 10}},
+			want:   []RegistryEvent{{Type: "added", Agent: bob, PID: 10}, {Type: "crashed", Agent: bob, Crash: crash}, {Type: "removed", Agent: bob, PrevPID: 10}},
+		},
+	}
+	for _, tt := range tests {
+		var pending []RegistryEvent
+		for _, e := range tt.events {
+			pending = coalesceInto(pending, e)
+		}
+		if fmt.Sprint(pending) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, pending, tt.want)
+		}
+	}
+}
+
+// takeEvents returns the events update queued, without delivering them.
+func takeEvents(r *Registry) []RegistryEvent {
+	r.events.mu.Lock()
+	defer r.events.mu.Unlock()
+	events := r.events.pending
+	r.events.pending = nil
+	return events
+}
+
+func TestUpdateDetectsRestarts(t *testing.T) {
+	const bob = "gt-myrig-crew-bob"
+	type scan struct {
+		pid     int  // bob's pane process; 0 = bob not found
+		session bool // bob's session still exists
+		expect  bool // a respawn is announced before the scan
+		age     time.Duration
+		want    string // "type prevPID pid", or "" for no event
+	}
+	tests := []struct {
+		name  string
+		scans []scan
+	}{
+		{"new agent", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{pid: 10, session: true, want: ""},
+		}},
+		{"session gone", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{want: "removed 10 0"},
+		}},
+		{"process replaced in place", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{pid: 11, session: true, want: "restarted 10 11"},
+		}},
+		{"exited back to the shell", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{session: true, want: "removed 10 0"},
+			{pid: 11, session: true, want: "added 0 11"},
+		}},
+		{"respawned within the restart window", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{session: true, expect: true, want: ""},
+			{pid: 11, session: true, age: restartWindow / 2, want: "restarted 10 11"},
+		}},
+		{"respawned in place", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{pid: 11, session: true, expect: true, want: "restarted 10 11"},
+			{session: true, want: "removed 11 0"},
+		}},
+		{"restart window expires", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{session: true, expect: true, want: ""},
+			{session: true, age: restartWindow, want: "removed 10 0"},
+			{pid: 11, session: true, want: "added 0 11"},
+		}},
+		{"session closed while restarting", []scan{
+			{pid: 10, session: true, want: "added 0 10"},
+			{session: true, expect: true, want: ""},
+			{want: "removed 10 0"},
+		}},
+	}
+	for _, tt := range tests {
+		r := NewRegistry(nil, "", DiscoveryRules{})
+		for i, sc := range tt.scans {
+			if sc.expect {
+				r.ExpectRestart(bob)
+			}
+			if pending, ok := r.restarting[bob]; ok && sc.age > 0 {
+				pending.since = pending.since.Add(-sc.age)
+				r.restarting[bob] = pending
+			}
+			discovered, pids := map[string]Agent{}, map[string]int{}
+			if sc.pid != 0 {
+				discovered[bob] = Agent{Name: bob}
+				pids[bob] = sc.pid
+			}
+			r.update(discovered, pids, map[string]bool{bob: sc.session})
+
+			var got []string
+			for _, e := range takeEvents(r) {
+				got = append(got, fmt.Sprintf("%s %d %d", e.Type, e.PrevPID, e.PID))
+			}
+			if strings.Join(got, ", ") != sc.want {
+				t.Errorf("%s: scan %d: events %q, want %q", tt.name, i, got, sc.want)
+			}
+			if _, live := r.GetAgent(bob); live != (sc.pid != 0) {
+				t.Errorf("%s: scan %d: GetAgent = %v", tt.name, i, live)
+			}
+		}
+		close(r.stopCh)
+	}
+}
+
+// A consumer that stops reading events must not block scans or lookups, and
+// the events waiting for it must not pile up.
+func TestUpdateWithSlowConsumer(t *testing.T) {
+	r := NewRegistry(nil, "", DiscoveryRules{})
+	go r.events.run(r.stopCh)
+	defer close(r.stopCh)
+
+	names := []string{"hq-mayor", "hq-deacon", "gt-myrig-witness"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 1000 {
+			discovered := map[string]Agent{}
+			pids := map[string]int{}
+			for j, name := range names {
+				if (i+j)%3 == 0 {
+					continue // churn: agents come and go
+				}
+				discovered[name] = Agent{Name: name, Attached: i%2 == 0}
+				pids[name] = 100 + j
+			}
+			r.update(discovered, pids, map[string]bool{})
+			if _, ok := r.GetAgent(names[0]); ok != (i%3 != 0) {
+				t.Errorf("scan %d: GetAgent(%s) = %v", i, names[0], ok)
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scans blocked on a consumer that is not reading")
+	}
+	if n := r.events.len(); n > 2*len(names) {
+		t.Errorf("%d events queued for %d agents; want them coalesced", n, len(names))
+	}
+
+	// Once the consumer catches up it sees each agent's net state.
+	seen := map[string]string{}
+	for drained := false; !drained; {
+		select {
+		case e := <-r.Events():
+			seen[e.Agent.Name] = e.Type
+		case <-time.After(200 * time.Millisecond):
+			drained = true
+		}
+	}
+	if n := r.events.len(); n > 0 {
+		t.Fatalf("%d events were not delivered", n)
+	}
+	for _, name := range names {
+		_, live := r.GetAgent(name)
+		got := seen[name]
+		if live && (got == "" || got == "removed") {
+			t.Errorf("%s is live but its last event is %q", name, got)
+		}
+		if !live && got != "" && got != "removed" {
+			t.Errorf("%s is gone but its last event is %q", name, got)
+		}
+	}
+}
+
+func TestEventQueueDeliversInOrder(t *testing.T) {
+	q := newEventQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+	go q.run(stop)
+
+	names := []string{"hq-mayor", "hq-deacon", "gt-boot"}
+	for _, name := range names {
+		q.push(RegistryEvent{Type: "added", Agent: Agent{Name: name}})
+	}
+	for _, want := range names {
+		select {
+		case e := <-q.out:
+			if e.Agent.Name != want {
+				t.Fatalf("got %s, want %s", e.Agent.Name, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not delivered", want)
+		}
+	}
+}
+
+func TestDebouncerMergesBursts(t *testing.T) {
+	d := debouncer{quiet: 20 * time.Millisecond, maxDelay: 100 * time.Millisecond}
+	defer d.stop()
+	if d.C() != nil {
+		t.Fatal("idle debouncer has a pending action")
+	}
+
+	// A single trigger fires after the quiet period.
+	start := time.Now()
+	d.trigger()
+	<-d.C()
+	d.fired()
+	if elapsed := time.Since(start); elapsed < d.quiet {
+		t.Fatalf("fired after %s, before the %s quiet period", elapsed, d.quiet)
+	}
+
+	// A steady stream of triggers still fires every maxDelay.
+	fires := 0
+	tick := time.NewTicker(5 * time.Millisecond)
+	defer tick.Stop()
+	deadline := time.After(350 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case <-tick.C:
+			d.trigger()
+		case <-d.C():
+			d.fired()
+			fires++
+		case <-deadline:
+			done = true
+		}
+	}
+	if fires < 2 || fires > 5 {
+		t.Fatalf("%d fires in 350ms of triggers every 5ms; want about one per 100ms", fires)
+	}
+}
@@ -0,0 +1,66 @@
+// Package ansi provides helpers for working with terminal output that contains
+// ANSI/VT escape sequences, as produced by pipe-pane and capture-pane -e.
+package ansi
+
+import "strings"
+
+// Strip removes escape sequences (CSI, OSC, DCS and two-byte ESC sequences) and
+// C0 control characters from s. Newlines and tabs are preserved.
+func Strip(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0x1b:
+			i = skipEscape(s, i)
+		case c == '\n' || c == '\t':
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			// Drop remaining control characters (CR, BS, BEL, ...)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// skipEscape returns the index of the last byte of the escape sequence that
+// starts at s[i] (which must be ESC).
+func skipEscape(s string, i int) int {
+	if i+1 >= len(s) {
+		return i
+	}
+
+	switch s[i+1] {
+	case '[':
+		// CSI: parameters and intermediates, terminated by a byte in 0x40–0x7E
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7e {
+				return j
+			}
+		}
+		return len(s) - 1
+	case ']', 'P', '_', '^', 'X':
+		// OSC/DCS/APC/PM/SOS: terminated by BEL or ST (ESC \)
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == 0x07 {
+				return j
+			}
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 1
+			}
+		}
+		return len(s) - 1
+	case '(', ')', '*', '+', '#', '%':
+		// Charset designation and similar: ESC + intermediate + final
+		if i+2 < len(s) {
+			return i + 2
+		}
+		return len(s) - 1
+	default:
+		// Two-byte sequence (ESC 7, ESC 8, ESC =, ESC M, ...)
+		return i + 1
+	}
+}
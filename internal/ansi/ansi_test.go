@@ -0,0 +1,30 @@
+package ansi
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "hello world", want: "hello world"},
+		{name: "sgr", in: "\x1b[1;31merror\x1b[0m: boom", want: "error: boom"},
+		{name: "cursor_movement", in: "\x1b[2J\x1b[Hprompt> \x1b[K", want: "prompt> "},
+		{name: "osc_bel", in: "\x1b]0;title\x07text", want: "text"},
+		{name: "osc_st", in: "\x1b]8;;https://x\x1b\\link\x1b]8;;\x1b\\", want: "link"},
+		{name: "charset", in: "\x1b(Bok", want: "ok"},
+		{name: "controls", in: "a\rb\x08c\x07\td\n", want: "abc\td\n"},
+		{name: "private_mode", in: "\x1b[?25lhidden\x1b[?25h", want: "hidden"},
+		{name: "truncated", in: "tail\x1b[3", want: "tail"},
+		{name: "utf8", in: "\x1b[32m✓\x1b[0m done", want: "✓ done"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Strip(tc.in); got != tc.want {
+				t.Fatalf("Strip(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
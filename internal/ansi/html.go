@@ -0,0 +1,83 @@
+package ansi
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Default terminal colors used when a style must be rendered concretely
+// (reverse video with default colors, or image output).
+var (
+	DefaultFG = [3]uint8{0xe5, 0xe5, 0xe5}
+	DefaultBG = [3]uint8{0x00, 0x00, 0x00}
+)
+
+// LinesToHTML converts lines of capture-pane -e output into HTML fragments,
+// one per input line, with colors and attributes preserved as inline-styled
+// spans. SGR state carries across lines, as it does in capture-pane output.
+func LinesToHTML(lines []string) []string {
+	out := make([]string, len(lines))
+	var st Style
+	for i, line := range lines {
+		var segs []Segment
+		segs, st = Segments(line, st)
+
+		var b strings.Builder
+		for _, seg := range segs {
+			css := seg.Style.CSS()
+			text := html.EscapeString(seg.Text)
+			if css == "" {
+				b.WriteString(text)
+				continue
+			}
+			fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, text)
+		}
+		out[i] = b.String()
+	}
+	return out
+}
+
+// CSS returns inline CSS declarations for the style, or "" for the default style.
+func (s Style) CSS() string {
+	var decls []string
+
+	fg, fgSet := s.FG, s.FG.Kind != ColorDefault
+	bg, bgSet := s.BG, s.BG.Kind != ColorDefault
+	if s.Reverse {
+		fg, bg = bg, fg
+		fgSet, bgSet = true, true
+		if fg.Kind == ColorDefault {
+			fg = Color{Kind: ColorRGB, R: DefaultBG[0], G: DefaultBG[1], B: DefaultBG[2]}
+		}
+		if bg.Kind == ColorDefault {
+			bg = Color{Kind: ColorRGB, R: DefaultFG[0], G: DefaultFG[1], B: DefaultFG[2]}
+		}
+	}
+	if fgSet {
+		r, g, b, _ := fg.RGB()
+		decls = append(decls, fmt.Sprintf("color:#%02x%02x%02x", r, g, b))
+	}
+	if bgSet {
+		r, g, b, _ := bg.RGB()
+		decls = append(decls, fmt.Sprintf("background-color:#%02x%02x%02x", r, g, b))
+	}
+	if s.Bold {
+		decls = append(decls, "font-weight:bold")
+	}
+	if s.Dim {
+		decls = append(decls, "opacity:0.6")
+	}
+	if s.Italic {
+		decls = append(decls, "font-style:italic")
+	}
+	switch {
+	case s.Underline && s.Strike:
+		decls = append(decls, "text-decoration:underline line-through")
+	case s.Underline:
+		decls = append(decls, "text-decoration:underline")
+	case s.Strike:
+		decls = append(decls, "text-decoration:line-through")
+	}
+	return strings.Join(decls, ";")
+}
@@ -0,0 +1,209 @@
+package ansi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColorKind distinguishes the terminal default color from palette and RGB colors.
+type ColorKind uint8
+
+const (
+	ColorDefault ColorKind = iota
+	ColorIndexed           // 256-color palette entry (0–15 are the ANSI colors)
+	ColorRGB               // 24-bit truecolor
+)
+
+// Color is a terminal foreground or background color.
+type Color struct {
+	Kind    ColorKind
+	Index   uint8
+	R, G, B uint8
+}
+
+// Style is the SGR rendition state applied to a run of text.
+type Style struct {
+	FG, BG    Color
+	Bold      bool
+	Dim       bool
+	Italic    bool
+	Underline bool
+	Reverse   bool
+	Strike    bool
+}
+
+// Segment is a run of text sharing a single Style.
+type Segment struct {
+	Text  string
+	Style Style
+}
+
+// Segments splits a line of capture-pane -e output into styled text runs.
+// SGR sequences update the current style starting from st; all other escape
+// sequences and control characters are dropped. The returned Style is the
+// state at the end of the line, so callers can carry it across lines.
+func Segments(line string, st Style) ([]Segment, Style) {
+	var segs []Segment
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			segs = append(segs, Segment{Text: text.String(), Style: st})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == 0x1b:
+			end := skipEscape(line, i)
+			if i+1 < len(line) && line[i+1] == '[' && line[end] == 'm' {
+				flush()
+				st = applySGR(st, line[i+2:end])
+			}
+			i = end
+		case c == '\t':
+			text.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			// Drop control characters
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flush()
+	return segs, st
+}
+
+// applySGR applies the semicolon-separated parameters of an SGR sequence.
+func applySGR(st Style, params string) Style {
+	if params == "" {
+		return Style{}
+	}
+
+	fields := strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' })
+	codes := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			n = 0
+		}
+		codes = append(codes, n)
+	}
+	if len(codes) == 0 {
+		return Style{}
+	}
+
+	for i := 0; i < len(codes); i++ {
+		code := codes[i]
+		switch {
+		case code == 0:
+			st = Style{}
+		case code == 1:
+			st.Bold = true
+		case code == 2:
+			st.Dim = true
+		case code == 3:
+			st.Italic = true
+		case code == 4:
+			st.Underline = true
+		case code == 7:
+			st.Reverse = true
+		case code == 9:
+			st.Strike = true
+		case code == 21 || code == 22:
+			st.Bold, st.Dim = false, false
+		case code == 23:
+			st.Italic = false
+		case code == 24:
+			st.Underline = false
+		case code == 27:
+			st.Reverse = false
+		case code == 29:
+			st.Strike = false
+		case code >= 30 && code <= 37:
+			st.FG = Color{Kind: ColorIndexed, Index: uint8(code - 30)}
+		case code == 39:
+			st.FG = Color{}
+		case code >= 40 && code <= 47:
+			st.BG = Color{Kind: ColorIndexed, Index: uint8(code - 40)}
+		case code == 49:
+			st.BG = Color{}
+		case code >= 90 && code <= 97:
+			st.FG = Color{Kind: ColorIndexed, Index: uint8(code - 90 + 8)}
+		case code >= 100 && code <= 107:
+			st.BG = Color{Kind: ColorIndexed, Index: uint8(code - 100 + 8)}
+		case code == 38 || code == 48:
+			color, consumed := parseExtendedColor(codes[i+1:])
+			i += consumed
+			if code == 38 {
+				st.FG = color
+			} else {
+				st.BG = color
+			}
+		}
+	}
+	return st
+}
+
+// parseExtendedColor parses the arguments following 38/48: "5;N" or "2;R;G;B".
+func parseExtendedColor(args []int) (Color, int) {
+	if len(args) == 0 {
+		return Color{}, 0
+	}
+	switch args[0] {
+	case 5:
+		if len(args) >= 2 {
+			return Color{Kind: ColorIndexed, Index: clampByte(args[1])}, 2
+		}
+	case 2:
+		if len(args) >= 4 {
+			return Color{Kind: ColorRGB, R: clampByte(args[1]), G: clampByte(args[2]), B: clampByte(args[3])}, 4
+		}
+	}
+	return Color{}, len(args)
+}
+
+func clampByte(n int) uint8 {
+	if n < 0 {
+		return 0
+	}
+	if n > 255 {
+		return 255
+	}
+	return uint8(n)
+}
+
+// ansiPalette holds the xterm default RGB values for the 16 ANSI colors.
+var ansiPalette = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// RGB resolves a palette or truecolor Color to RGB components using the
+// xterm 256-color palette. ok is false for the default color.
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	switch c.Kind {
+	case ColorRGB:
+		return c.R, c.G, c.B, true
+	case ColorIndexed:
+		i := int(c.Index)
+		switch {
+		case i < 16:
+			p := ansiPalette[i]
+			return p[0], p[1], p[2], true
+		case i < 232:
+			// 6x6x6 color cube
+			i -= 16
+			levels := [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+			return levels[i/36], levels[(i/6)%6], levels[i%6], true
+		default:
+			// Grayscale ramp
+			v := uint8(8 + (i-232)*10)
+			return v, v, v, true
+		}
+	}
+	return 0, 0, 0, false
+}
@@ -0,0 +1,88 @@
+package ansi
+
+import "testing"
+
+func TestSegments(t *testing.T) {
+	segs, end := Segments("plain \x1b[1;31mred bold\x1b[22m red\x1b[0m done", Style{})
+	if len(segs) != 4 {
+		t.Fatalf("len(segs) = %d, want 4: %+v", len(segs), segs)
+	}
+	if segs[0].Text != "plain " || segs[0].Style != (Style{}) {
+		t.Fatalf("segs[0] = %+v, want default-styled plain text", segs[0])
+	}
+	red := Color{Kind: ColorIndexed, Index: 1}
+	if segs[1].Text != "red bold" || !segs[1].Style.Bold || segs[1].Style.FG != red {
+		t.Fatalf("segs[1] = %+v, want bold red", segs[1])
+	}
+	if segs[2].Text != " red" || segs[2].Style.Bold || segs[2].Style.FG != red {
+		t.Fatalf("segs[2] = %+v, want non-bold red", segs[2])
+	}
+	if segs[3].Text != " done" || end != (Style{}) {
+		t.Fatalf("segs[3] = %+v end = %+v, want reset style", segs[3], end)
+	}
+}
+
+func TestSegmentsExtendedColors(t *testing.T) {
+	segs, _ := Segments("\x1b[38;5;208;48;2;1;2;3mx", Style{})
+	if len(segs) != 1 {
+		t.Fatalf("len(segs) = %d, want 1", len(segs))
+	}
+	st := segs[0].Style
+	if st.FG != (Color{Kind: ColorIndexed, Index: 208}) {
+		t.Fatalf("FG = %+v, want palette 208", st.FG)
+	}
+	if st.BG != (Color{Kind: ColorRGB, R: 1, G: 2, B: 3}) {
+		t.Fatalf("BG = %+v, want rgb(1,2,3)", st.BG)
+	}
+}
+
+func TestSegmentsCarryStyle(t *testing.T) {
+	_, st := Segments("\x1b[32mgreen", Style{})
+	segs, _ := Segments("still green", st)
+	if len(segs) != 1 || segs[0].Style.FG != (Color{Kind: ColorIndexed, Index: 2}) {
+		t.Fatalf("segs = %+v, want carried green style", segs)
+	}
+}
+
+func TestColorRGB(t *testing.T) {
+	cases := []struct {
+		c       Color
+		r, g, b uint8
+	}{
+		{Color{Kind: ColorIndexed, Index: 9}, 0xff, 0x00, 0x00},
+		{Color{Kind: ColorIndexed, Index: 16}, 0x00, 0x00, 0x00},
+		{Color{Kind: ColorIndexed, Index: 231}, 0xff, 0xff, 0xff},
+		{Color{Kind: ColorIndexed, Index: 232}, 0x08, 0x08, 0x08},
+		{Color{Kind: ColorRGB, R: 10, G: 20, B: 30}, 10, 20, 30},
+	}
+	for _, tc := range cases {
+		r, g, b, ok := tc.c.RGB()
+		if !ok || r != tc.r || g != tc.g || b != tc.b {
+			t.Fatalf("%+v.RGB() = %d,%d,%d,%v want %d,%d,%d", tc.c, r, g, b, ok, tc.r, tc.g, tc.b)
+		}
+	}
+	if _, _, _, ok := (Color{}).RGB(); ok {
+		t.Fatal("default color should report ok=false")
+	}
+}
+
+func TestLinesToHTML(t *testing.T) {
+	got := LinesToHTML([]string{"a<b \x1b[1;32mok", "next\x1b[0m &"})
+	want := []string{
+		`a&lt;b <span style="color:#00cd00;font-weight:bold">ok</span>`,
+		`<span style="color:#00cd00;font-weight:bold">next</span> &amp;`,
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStyleCSSReverseDefaults(t *testing.T) {
+	got := Style{Reverse: true}.CSS()
+	want := "color:#000000;background-color:#e5e5e5"
+	if got != want {
+		t.Fatalf("CSS() = %q, want %q", got, want)
+	}
+}
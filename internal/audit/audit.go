@@ -0,0 +1,333 @@
+// Package audit records mutating actions (prompts, keyboard input, uploads,
+// resizes, kills) to an append-only JSONL file with size-based rotation.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Results recorded in Entry.Result.
+const (
+	ResultOK     = "ok"
+	ResultError  = "error"
+	ResultDenied = "denied"
+)
+
+// keyFlushDelay is how long keyboard input from one actor to one agent is
+// coalesced into a single "keys" entry.
+const keyFlushDelay = 2 * time.Second
+
+// Entry is one audited action.
+type Entry struct {
+	Time   time.Time      `json:"time"`
+	Actor  string         `json:"actor"`            // identity name
+	Issuer string         `json:"issuer,omitempty"` // minter of the actor's signed token
+	Remote string         `json:"remote,omitempty"`
+	Action string         `json:"action"`
+	Agent  string         `json:"agent,omitempty"`
+	Detail map[string]any `json:"detail,omitempty"`
+	Result string         `json:"result"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Outcome maps an action's error to an Entry result and error message.
+func Outcome(err error) (result, errMsg string) {
+	if err != nil {
+		return ResultError, err.Error()
+	}
+	return ResultOK, ""
+}
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	Actor  string
+	Agent  string // agent name or glob pattern
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int // maximum entries, newest first
+
+	// Visible, if set, hides entries about agents it returns false for.
+	Visible func(agent string) bool
+}
+
+// Logger appends entries to a JSONL file. A nil *Logger discards entries, so
+// callers need not check whether auditing is enabled.
+type Logger struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	keys map[keyTallyKey]*keyTally
+}
+
+type keyTallyKey struct{ actor, agent string }
+
+type keyTally struct {
+	entry Entry
+	keys  int
+	bytes int
+	timer *time.Timer
+}
+
+// Open opens (or creates) the audit log at path. When the file would grow
+// past maxBytes it is rotated to path.1, path.2, ... keeping maxFiles old
+// files. maxBytes <= 0 disables rotation.
+func Open(path string, maxBytes int64, maxFiles int) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	l := &Logger{path: path, maxBytes: maxBytes, maxFiles: maxFiles, keys: make(map[keyTallyKey]*keyTally)}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) openFile() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Record appends an entry, stamping Time if unset.
+func (l *Logger) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(e)
+}
+
+// CountKeys records keyboard input. Frames from the same actor to the same
+// agent are coalesced into one "keys" entry per keyFlushDelay, since logging
+// every keystroke would drown out everything else (and the keys themselves
+// are deliberately not recorded).
+func (l *Logger) CountKeys(actor, remote, agent string, bytes int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := keyTallyKey{actor: actor, agent: agent}
+	t, ok := l.keys[k]
+	if !ok {
+		t = &keyTally{entry: Entry{
+			Time:   time.Now().UTC(),
+			Actor:  actor,
+			Remote: remote,
+			Action: "keys",
+			Agent:  agent,
+			Result: ResultOK,
+		}}
+		l.keys[k] = t
+		t.timer = time.AfterFunc(keyFlushDelay, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.flushKeys(k)
+		})
+	}
+	t.keys++
+	t.bytes += bytes
+}
+
+// flushKeys writes a pending key tally. Caller holds l.mu.
+func (l *Logger) flushKeys(k keyTallyKey) {
+	t, ok := l.keys[k]
+	if !ok {
+		return
+	}
+	delete(l.keys, k)
+	t.timer.Stop()
+	t.entry.Detail = map[string]any{"keys": t.keys, "bytes": t.bytes}
+	l.write(t.entry)
+}
+
+// write appends one entry, rotating first if needed. Caller holds l.mu.
+func (l *Logger) write(e Entry) {
+	if l.f == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("audit: marshal: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			log.Printf("audit: rotate: %v", err)
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("audit: write: %v", err)
+	}
+}
+
+// rotate shifts path.N-1 → path.N, ..., path → path.1 and reopens path.
+func (l *Logger) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	if l.maxFiles <= 0 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return l.openFile()
+	}
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Close flushes pending key tallies and closes the file.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k := range l.keys {
+		l.flushKeys(k)
+	}
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// Query returns entries matching f from the current and rotated files,
+// newest first.
+func (l *Logger) Query(f Filter) ([]Entry, error) {
+	if f.Agent != "" {
+		if _, err := path.Match(f.Agent, ""); err != nil {
+			return nil, fmt.Errorf("invalid agent pattern: %w", err)
+		}
+	}
+
+	l.mu.Lock()
+	files := []string{l.path}
+	for i := 1; i <= l.maxFiles; i++ {
+		files = append(files, rotatedPath(l.path, i))
+	}
+	l.mu.Unlock()
+
+	var entries []Entry
+	for _, name := range files {
+		found, err := readEntries(name, f)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+func readEntries(name string, f Filter) ([]Entry, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // tolerate a torn final line
+		}
+		if f.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (f Filter) matches(e Entry) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Agent != "" {
+		if ok, _ := path.Match(f.Agent, e.Agent); !ok {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	if f.Visible != nil && e.Agent != "" && !f.Visible(e.Agent) {
+		return false
+	}
+	return true
+}
+
+// maxPromptChars bounds the prompt text kept in an entry.
+const maxPromptChars = 4096
+
+// PromptDetail summarizes a prompt for Entry.Detail.
+func PromptDetail(prompt string) map[string]any {
+	text := prompt
+	if r := []rune(text); len(r) > maxPromptChars {
+		text = string(r[:maxPromptChars]) + "…"
+	}
+	return map[string]any{"prompt": text, "length": len(prompt)}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	result, errMsg := Outcome(errors.New("agent not found"))
+	l.Record(Entry{Time: base, Actor: "alice", Action: "prompt", Agent: "gt-myrig-crew-bob", Result: ResultOK})
+	l.Record(Entry{Time: base.Add(time.Minute), Actor: "bob", Action: "kill", Agent: "hq-mayor", Result: result, Error: errMsg})
+	l.Record(Entry{Time: base.Add(2 * time.Minute), Actor: "alice", Action: "resize", Agent: "gt-myrig-crew-bob", Result: ResultOK})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // actions, newest first
+	}{
+		{name: "all", filter: Filter{}, want: []string{"resize", "kill", "prompt"}},
+		{name: "actor", filter: Filter{Actor: "alice"}, want: []string{"resize", "prompt"}},
+		{name: "agent glob", filter: Filter{Agent: "hq-*"}, want: []string{"kill"}},
+		{name: "action", filter: Filter{Action: "prompt"}, want: []string{"prompt"}},
+		{name: "since", filter: Filter{Since: base.Add(time.Minute)}, want: []string{"resize", "kill"}},
+		{name: "limit", filter: Filter{Limit: 1}, want: []string{"resize"}},
+		{name: "visible", filter: Filter{Visible: func(a string) bool { return a != "hq-mayor" }}, want: []string{"resize", "prompt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Action)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("actions = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("actions = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotationKeepsMaxFiles(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(p, 200, 2)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 20; i++ {
+		l.Record(Entry{Actor: "alice", Action: "prompt", Agent: "gt-myrig-crew-bob", Result: ResultOK})
+	}
+
+	for _, name := range []string{p, p + ".1", p + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+		if info.Size() > 200 {
+			t.Fatalf("%s is %d bytes, want <= 200", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(p + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected no %s.3, stat err = %v", p, err)
+	}
+
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= 20 {
+		t.Fatalf("len(entries) = %d, want the retained subset", len(entries))
+	}
+}
+
+func TestCountKeysCoalesces(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	l.CountKeys("alice", "127.0.0.1:5000", "gt-myrig-crew-bob", 1)
+	l.CountKeys("alice", "127.0.0.1:5000", "gt-myrig-crew-bob", 3)
+	l.CountKeys("bob", "127.0.0.1:5001", "gt-myrig-crew-bob", 1)
+	if err := l.Close(); err != nil { // Close flushes pending tallies
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	entries, err := l.Query(Filter{Actor: "alice"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "keys" {
+		t.Fatalf("entries = %+v, want one keys entry", entries)
+	}
+	if d := entries[0].Detail; d["keys"] != float64(2) || d["bytes"] != float64(4) {
+		t.Fatalf("detail = %v, want keys=2 bytes=4", d)
+	}
+}
+
+func TestNilLoggerDiscards(t *testing.T) {
+	var l *Logger
+	l.Record(Entry{Actor: "alice"})
+	l.CountKeys("alice", "", "x", 1)
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+)
+
+// AgentRef identifies an agent for access checks.
+type AgentRef struct {
+	Name string
+	Role string
+	Rig  string // empty for town-level agents
+}
+
+// RefOf returns a live agent's identity for access checks.
+func RefOf(a agents.Agent) AgentRef {
+	ref := AgentRef{Name: a.Name, Role: a.Role}
+	if a.Rig != nil {
+		ref.Rig = *a.Rig
+	}
+	return ref
+}
+
+// SessionRef derives an agent's identity from its session name alone, for
+// agents that are not running (e.g. recorded transcripts).
+func SessionRef(name string) AgentRef {
+	role, rig := agents.ParseSessionName(name)
+	return AgentRef{Name: name, Role: role, Rig: rig}
+}
+
+// VisibleAgents filters agents down to those the identity may see.
+func VisibleAgents(id Identity, all []agents.Agent) []agents.Agent {
+	if !id.Restricted() {
+		return all
+	}
+	visible := make([]agents.Agent, 0, len(all))
+	for _, a := range all {
+		if id.CanSee(RefOf(a)) {
+			visible = append(visible, a)
+		}
+	}
+	return visible
+}
+
+// Rule grants access to the agents it matches. Each non-empty list must
+// contain a match (glob patterns, as in path.Match); an empty list matches
+// anything. Scope defaults to the identity's own scope and can only narrow it.
+type Rule struct {
+	Rigs   []string `json:"rigs,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	Agents []string `json:"agents,omitempty"`
+	Scope  Scope    `json:"scope,omitempty"`
+}
+
+func (r Rule) validate() error {
+	for _, patterns := range [][]string{r.Rigs, r.Roles, r.Agents} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Rule) matches(a AgentRef) bool {
+	return matchAny(r.Rigs, a.Rig) && matchAny(r.Roles, a.Role) && matchAny(r.Agents, a.Name)
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Restricted reports whether the identity is limited to a subset of agents.
+func (id Identity) Restricted() bool {
+	return len(id.Rules) > 0
+}
+
+// ScopeFor returns the identity's effective scope on an agent: its own scope
+// if unrestricted, otherwise the highest scope among matching rules (capped at
+// its own), or ScopeNone if no rule matches.
+func (id Identity) ScopeFor(a AgentRef) Scope {
+	if !id.Restricted() {
+		return id.Scope
+	}
+	best := ScopeNone
+	for _, r := range id.Rules {
+		if !r.matches(a) {
+			continue
+		}
+		s := r.Scope
+		if s == ScopeNone || s > id.Scope {
+			s = id.Scope
+		}
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// CanSee reports whether the agent is visible to the identity at all. Agents
+// an identity cannot see are reported as not found rather than forbidden.
+func (id Identity) CanSee(a AgentRef) bool {
+	return id.ScopeFor(a).Allows(ScopeRead)
+}
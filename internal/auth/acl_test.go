@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+)
+
+func TestIdentityScopeFor(t *testing.T) {
+	contractor := Identity{Name: "contractor", Scope: ScopeOperate, Rules: []Rule{
+		{Rigs: []string{"myrig"}},
+		{Agents: []string{"hq-*"}, Scope: ScopeRead},
+		{Rigs: []string{"other"}, Roles: []string{"crew"}, Scope: ScopeAdmin},
+	}}
+
+	tests := []struct {
+		agent AgentRef
+		want  Scope
+	}{
+		{agent: AgentRef{Name: "gt-myrig-crew-bob", Role: "crew", Rig: "myrig"}, want: ScopeOperate},
+		{agent: AgentRef{Name: "hq-mayor", Role: "mayor"}, want: ScopeRead},
+		{agent: AgentRef{Name: "gt-other-crew-al", Role: "crew", Rig: "other"}, want: ScopeOperate}, // capped at token scope
+		{agent: AgentRef{Name: "gt-other-witness", Role: "witness", Rig: "other"}, want: ScopeNone},
+	}
+	for _, tt := range tests {
+		if got := contractor.ScopeFor(tt.agent); got != tt.want {
+			t.Fatalf("ScopeFor(%s) = %s, want %s", tt.agent.Name, got, tt.want)
+		}
+	}
+	if contractor.CanSee(AgentRef{Name: "gt-other-witness", Rig: "other"}) {
+		t.Fatal("agent outside all rules must be invisible")
+	}
+
+	admin := Identity{Name: "ci", Scope: ScopeAdmin}
+	if got := admin.ScopeFor(AgentRef{Name: "anything"}); got != ScopeAdmin {
+		t.Fatalf("unrestricted ScopeFor = %s, want admin", got)
+	}
+}
+
+func TestVisibleAgents(t *testing.T) {
+	myrig := "myrig"
+	all := []agents.Agent{
+		{Name: "gt-myrig-crew-bob", Role: "crew", Rig: &myrig},
+		{Name: "hq-mayor", Role: "mayor"},
+	}
+	crew := Identity{Name: "crew", Scope: ScopeRead, Rules: []Rule{{Roles: []string{"crew"}}}}
+	visible := VisibleAgents(crew, all)
+	if len(visible) != 1 || visible[0].Name != "gt-myrig-crew-bob" {
+		t.Fatalf("VisibleAgents() = %+v, want only gt-myrig-crew-bob", visible)
+	}
+	if got := VisibleAgents(Identity{Scope: ScopeRead}, all); len(got) != 2 {
+		t.Fatalf("unrestricted VisibleAgents() = %d agents, want 2", len(got))
+	}
+
+	// A departed agent's session name yields the same ref as the live agent.
+	if live, gone := RefOf(all[0]), SessionRef("gt-myrig-crew-bob"); live != gone {
+		t.Fatalf("RefOf() = %+v, SessionRef() = %+v", live, gone)
+	}
+}
+
+func TestNewAuthenticatorRejectsBadRulePattern(t *testing.T) {
+	_, err := NewAuthenticator("", []Token{{Name: "a", Token: "a", Scope: ScopeRead, Rules: []Rule{{Agents: []string{"["}}}}}, nil)
+	if err == nil {
+		t.Fatal("expected error for malformed glob")
+	}
+}
@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the authenticated identity.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns the identity stored by WithIdentity.
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
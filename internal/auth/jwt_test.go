@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSignerRoundTrip(t *testing.T) {
+	s, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	token, err := s.Mint(Claims{Subject: "browser-1", Scope: ScopeRead, Rules: []Rule{{Agents: []string{"gt-myrig-*"}}}}, now, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+
+	c, err := s.Verify(token, now.Add(14*time.Minute))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if c.Subject != "browser-1" || c.Scope != ScopeRead || len(c.Rules) != 1 || c.ExpiresAt != now.Add(15*time.Minute).Unix() {
+		t.Fatalf("claims = %+v", c)
+	}
+
+	if _, err := s.Verify(token, now.Add(15*time.Minute)); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Verify() after exp error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestSignerRejectsTampering(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	other, _ := NewSigner([]byte(strings.Repeat("x", MinSigningKeyBytes)))
+	now := time.Unix(1_800_000_000, 0)
+	token, _ := s.Mint(Claims{Subject: "browser-1", Scope: ScopeRead}, now, time.Minute)
+	parts := strings.Split(token, ".")
+
+	forged, _ := other.Mint(Claims{Subject: "browser-1", Scope: ScopeAdmin}, now, time.Minute)
+	noneHeader := "eyJhbGciOiJub25lIn0" // {"alg":"none"}
+
+	tests := map[string]string{
+		"wrong key":       forged,
+		"swapped payload": parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2],
+		"alg none":        noneHeader + "." + parts[1] + ".",
+		"missing segment": parts[0] + "." + parts[1],
+		"garbage":         "a.b.c",
+	}
+	for name, tok := range tests {
+		if _, err := s.Verify(tok, now); err == nil {
+			t.Fatalf("%s: Verify() succeeded, want error", name)
+		}
+	}
+}
+
+func TestNewSignerRequiresLongKey(t *testing.T) {
+	if _, err := NewSigner([]byte("short")); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
+
+func TestAuthenticatorAcceptsSignedTokens(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	a, err := NewAuthenticator("static", nil, s)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	token, _ := s.Mint(Claims{Subject: "backend/browser-1", Scope: ScopeOperate, Issuer: "backend"}, time.Now(), time.Minute)
+
+	req := httptest.NewRequest("GET", "http://localhost:8080/ws?token="+token, nil)
+	id, ok := a.Authenticate(req)
+	if !ok || id.Name != "backend/browser-1" || id.Scope != ScopeOperate || id.Issuer != "backend" {
+		t.Fatalf("Authenticate(signed) = %+v, %v", id, ok)
+	}
+
+	req = httptest.NewRequest("GET", "http://localhost:8080/ws", nil)
+	req.Header.Set("Authorization", "Bearer static")
+	if id, ok := a.Authenticate(req); !ok || id.Name != LegacyTokenName {
+		t.Fatalf("Authenticate(static) = %+v, %v", id, ok)
+	}
+
+	expired, _ := s.Mint(Claims{Subject: "old", Scope: ScopeRead}, time.Now().Add(-time.Hour), time.Minute)
+	req = httptest.NewRequest("GET", "http://localhost:8080/ws?token="+expired, nil)
+	if _, ok := a.Authenticate(req); ok {
+		t.Fatal("expired signed token was accepted")
+	}
+}
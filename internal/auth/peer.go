@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// ErrPeerCredUnsupported is returned by ReadPeerCred on platforms without
+// SO_PEERCRED.
+var ErrPeerCredUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// PeerCred is the kernel-reported identity of the process on the other end of
+// a Unix socket connection.
+type PeerCred struct {
+	PID int
+	UID int
+	GID int
+}
+
+type peerKey struct{}
+
+// WithPeer returns a context carrying the peer credentials of a Unix socket
+// connection (see http.Server.ConnContext).
+func WithPeer(ctx context.Context, cred PeerCred) context.Context {
+	return context.WithValue(ctx, peerKey{}, cred)
+}
+
+// PeerFrom returns the peer credentials stored by WithPeer.
+func PeerFrom(ctx context.Context) (PeerCred, bool) {
+	cred, ok := ctx.Value(peerKey{}).(PeerCred)
+	return cred, ok
+}
+
+// parsePeerRule parses a Token.Peer value of the form "uid:N" or "gid:N".
+func parsePeerRule(s string) (kind string, id int, err error) {
+	kind, num, ok := strings.Cut(s, ":")
+	if ok && (kind == "uid" || kind == "gid") {
+		if id, err = strconv.Atoi(num); err == nil && id >= 0 {
+			return kind, id, nil
+		}
+	}
+	return "", 0, fmt.Errorf("invalid peer %q: expected uid:N or gid:N", s)
+}
+
+func peerMatches(rule string, cred PeerCred) bool {
+	kind, id, err := parsePeerRule(rule)
+	if err != nil {
+		return false
+	}
+	if kind == "uid" {
+		return cred.UID == id
+	}
+	return cred.GID == id
+}
+
+// localUserName names the identity of same-user Unix socket callers.
+func localUserName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return "unix:" + u.Username
+	}
+	return "unix:uid-" + strconv.Itoa(uid)
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"net"
+	"syscall"
+)
+
+// ReadPeerCred returns the SO_PEERCRED credentials of a Unix socket connection.
+func ReadPeerCred(c *net.UnixConn) (PeerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return PeerCred{}, err
+	}
+	var ucred *syscall.Ucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, sockErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return PeerCred{}, err
+	}
+	if sockErr != nil {
+		return PeerCred{}, sockErr
+	}
+	return PeerCred{PID: int(ucred.Pid), UID: int(ucred.Uid), GID: int(ucred.Gid)}, nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPeerCred(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	cred, err := ReadPeerCred(server.(*net.UnixConn))
+	if err != nil {
+		t.Fatalf("ReadPeerCred() error = %v", err)
+	}
+	if cred.UID != os.Getuid() || cred.PID != os.Getpid() {
+		t.Fatalf("cred = %+v, want uid %d pid %d", cred, os.Getuid(), os.Getpid())
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package auth
+
+import "net"
+
+// ReadPeerCred is unsupported outside Linux.
+func ReadPeerCred(*net.UnixConn) (PeerCred, error) {
+	return PeerCred{}, ErrPeerCredUnsupported
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scope is the level of access a token grants. Each scope includes the ones
+// below it.
+type Scope int
+
+const (
+	ScopeNone    Scope = iota
+	ScopeRead          // list agents, subscribe to output and lifecycle events
+	ScopeOperate       // send prompts, keyboard input, resize and file uploads
+	ScopeAdmin         // spawn, restart and kill agents; set restart policies
+)
+
+var scopeNames = map[Scope]string{
+	ScopeNone:    "none",
+	ScopeRead:    "read",
+	ScopeOperate: "operate",
+	ScopeAdmin:   "admin",
+}
+
+// ParseScope parses "read", "operate" or "admin".
+func ParseScope(s string) (Scope, error) {
+	for scope, name := range scopeNames {
+		if scope != ScopeNone && name == s {
+			return scope, nil
+		}
+	}
+	return ScopeNone, fmt.Errorf("invalid scope %q: expected read, operate or admin", s)
+}
+
+func (s Scope) String() string {
+	if name, ok := scopeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("scope(%d)", int(s))
+}
+
+// Allows reports whether s grants at least the required scope.
+func (s Scope) Allows(required Scope) bool {
+	return s >= required
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Scope) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Scope) UnmarshalText(b []byte) error {
+	parsed, err := ParseScope(string(b))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Name  string `json:"name"`
+	Scope Scope  `json:"scope"`
+	Rules []Rule `json:"rules,omitempty"` // per-agent ACL; empty = all agents
+
+	// Issuer is the identity that minted a signed token; empty otherwise.
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// Token is a named API token from the tokens file. Rules, if present, limit
+// which agents the token can see and act on. Subject, if set, also lets a
+// verified client certificate with that subject (its CN or full DN)
+// authenticate as this identity, and Peer ("uid:N" or "gid:N") does the same
+// for Unix socket callers; Token may then be omitted.
+type Token struct {
+	Name    string `json:"name"`
+	Token   string `json:"token,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Peer    string `json:"peer,omitempty"`
+	Scope   Scope  `json:"scope"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// tokensFile is the on-disk format of --auth-tokens-file.
+type tokensFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// LoadTokensFile reads named tokens from a JSON file of the form
+// {"tokens":[{"name":"alice","token":"...","scope":"operate"}]}.
+func LoadTokensFile(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens file: %w", err)
+	}
+	var f tokensFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse tokens file %s: %w", path, err)
+	}
+	return f.Tokens, nil
+}
+
+// LegacyTokenName is the identity name of the --auth-token shared token.
+const LegacyTokenName = "auth-token"
+
+// AnonymousName is the identity name of every caller when auth is disabled.
+const AnonymousName = "anonymous"
+
+// Authenticator resolves request credentials to an Identity.
+type Authenticator struct {
+	tokens []Token
+	signer *Signer // nil when signed tokens are disabled
+	uid    int     // Unix socket callers with this uid are trusted as admin
+	now    func() time.Time
+}
+
+// NewAuthenticator creates an Authenticator from the legacy shared token (if
+// non-empty, it grants admin), named tokens and an optional Signer for
+// short-lived signed tokens. With no tokens and no signer, every request is
+// authenticated as an anonymous admin, matching the adapter's behavior
+// without --auth-token. Unix socket callers running as the adapter's own user
+// are always authenticated as admin.
+func NewAuthenticator(legacyToken string, tokens []Token, signer *Signer) (*Authenticator, error) {
+	a := &Authenticator{signer: signer, uid: os.Getuid(), now: time.Now}
+	if t := strings.TrimSpace(legacyToken); t != "" {
+		a.tokens = append(a.tokens, Token{Name: LegacyTokenName, Token: t, Scope: ScopeAdmin})
+	}
+
+	seen := make(map[string]bool)
+	subjects := make(map[string]bool)
+	for i, t := range tokens {
+		t.Token = strings.TrimSpace(t.Token)
+		t.Subject = strings.TrimSpace(t.Subject)
+		t.Peer = strings.TrimSpace(t.Peer)
+		switch {
+		case t.Name == "":
+			return nil, fmt.Errorf("token %d: name required", i)
+		case t.Token == "" && t.Subject == "" && t.Peer == "":
+			return nil, fmt.Errorf("token %q: token, subject or peer required", t.Name)
+		case t.Subject != "" && subjects[t.Subject]:
+			return nil, fmt.Errorf("token %q: duplicate subject %q", t.Name, t.Subject)
+		case t.Scope == ScopeNone:
+			return nil, fmt.Errorf("token %q: scope required", t.Name)
+		case seen[t.Name] || t.Name == LegacyTokenName:
+			return nil, fmt.Errorf("token %q: duplicate name", t.Name)
+		}
+		for _, rule := range t.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("token %q: %w", t.Name, err)
+			}
+		}
+		if t.Peer != "" {
+			if _, _, err := parsePeerRule(t.Peer); err != nil {
+				return nil, fmt.Errorf("token %q: %w", t.Name, err)
+			}
+		}
+		seen[t.Name] = true
+		if t.Subject != "" {
+			subjects[t.Subject] = true
+		}
+		a.tokens = append(a.tokens, t)
+	}
+	return a, nil
+}
+
+// Open reports whether authentication is disabled.
+func (a *Authenticator) Open() bool {
+	return len(a.tokens) == 0 && a.signer == nil
+}
+
+// Signer returns the signer for minting tokens, or nil if disabled.
+func (a *Authenticator) Signer() *Signer {
+	return a.signer
+}
+
+// Authenticate resolves the request's Unix socket peer, verified client
+// certificate, bearer or ?token= credential, in that order.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, bool) {
+	if a.Open() {
+		return Identity{Name: AnonymousName, Scope: ScopeAdmin}, true
+	}
+	if id, ok := a.peerIdentity(r); ok {
+		return id, true
+	}
+	if id, ok := a.certIdentity(r); ok {
+		return id, true
+	}
+
+	var candidates []string
+	const bearerPrefix = "Bearer "
+	if authHeader := strings.TrimSpace(r.Header.Get("Authorization")); strings.HasPrefix(authHeader, bearerPrefix) {
+		candidates = append(candidates, strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix)))
+	}
+	candidates = append(candidates, strings.TrimSpace(r.URL.Query().Get("token")))
+
+	for _, c := range candidates {
+		if a.signer != nil && looksLikeJWT(c) {
+			if claims, err := a.signer.Verify(c, a.now()); err == nil {
+				return claims.Identity(), true
+			}
+			continue
+		}
+		// Compare against every token so timing does not reveal which matched.
+		var match *Token
+		for i := range a.tokens {
+			if tokensEqual(a.tokens[i].Token, c) && match == nil {
+				match = &a.tokens[i]
+			}
+		}
+		if match != nil {
+			return Identity{Name: match.Name, Scope: match.Scope, Rules: match.Rules}, true
+		}
+	}
+	return Identity{}, false
+}
+
+// certIdentity maps a verified client certificate to the token whose Subject
+// equals the leaf certificate's common name or full distinguished name.
+func (a *Authenticator) certIdentity(r *http.Request) (Identity, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	for _, t := range a.tokens {
+		if t.Subject != "" && (t.Subject == leaf.Subject.CommonName || t.Subject == leaf.Subject.String()) {
+			return Identity{Name: t.Name, Scope: t.Scope, Rules: t.Rules}, true
+		}
+	}
+	return Identity{}, false
+}
+
+// peerIdentity authenticates Unix socket callers: the adapter's own user is
+// an admin, other users need a token entry whose Peer matches their uid (or,
+// failing that, their gid).
+func (a *Authenticator) peerIdentity(r *http.Request) (Identity, bool) {
+	cred, ok := PeerFrom(r.Context())
+	if !ok {
+		return Identity{}, false
+	}
+	if cred.UID == a.uid {
+		return Identity{Name: localUserName(cred.UID), Scope: ScopeAdmin}, true
+	}
+	for _, kind := range []string{"uid:", "gid:"} {
+		for _, t := range a.tokens {
+			if strings.HasPrefix(t.Peer, kind) && peerMatches(t.Peer, cred) {
+				return Identity{Name: t.Name, Scope: t.Scope, Rules: t.Rules}, true
+			}
+		}
+	}
+	return Identity{}, false
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthenticatorScopes(t *testing.T) {
+	a, err := NewAuthenticator("legacy", []Token{
+		{Name: "observer", Token: "r-token", Scope: ScopeRead},
+		{Name: "ops", Token: "o-token", Scope: ScopeOperate},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		token     string
+		wantName  string
+		wantScope Scope
+		wantOK    bool
+	}{
+		{token: "legacy", wantName: LegacyTokenName, wantScope: ScopeAdmin, wantOK: true},
+		{token: "r-token", wantName: "observer", wantScope: ScopeRead, wantOK: true},
+		{token: "o-token", wantName: "ops", wantScope: ScopeOperate, wantOK: true},
+		{token: "wrong"},
+		{token: ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://localhost:8080/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		id, ok := a.Authenticate(req)
+		if ok != tt.wantOK || id.Name != tt.wantName || id.Scope != tt.wantScope {
+			t.Fatalf("Authenticate(%q) = %+v, %v; want %s/%s, %v", tt.token, id, ok, tt.wantName, tt.wantScope, tt.wantOK)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost:8080/ws?token=r-token", nil)
+	if id, ok := a.Authenticate(req); !ok || id.Name != "observer" {
+		t.Fatalf("query token: got %+v, %v", id, ok)
+	}
+}
+
+func TestAuthenticatorOpenWithoutTokens(t *testing.T) {
+	a, err := NewAuthenticator("", nil, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	id, ok := a.Authenticate(httptest.NewRequest("GET", "http://localhost:8080/ws", nil))
+	if !ok || id.Scope != ScopeAdmin {
+		t.Fatalf("Authenticate() = %+v, %v; want anonymous admin", id, ok)
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	if ScopeRead.Allows(ScopeOperate) {
+		t.Fatal("read must not allow operate")
+	}
+	if !ScopeAdmin.Allows(ScopeOperate) || !ScopeOperate.Allows(ScopeRead) {
+		t.Fatal("higher scopes must include lower ones")
+	}
+}
+
+func TestLoadTokensFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	data := `{"tokens":[{"name":"alice","token":"a","scope":"admin"},{"name":"bob","token":"b","scope":"read"}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := LoadTokensFile(path)
+	if err != nil {
+		t.Fatalf("LoadTokensFile() error = %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].Scope != ScopeAdmin || tokens[1].Scope != ScopeRead {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"tokens":[{"name":"x","token":"x","scope":"root"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTokensFile(path); err == nil {
+		t.Fatal("expected error for invalid scope")
+	}
+}
+
+func TestNewAuthenticatorRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []Token
+	}{
+		{name: "missing name", tokens: []Token{{Token: "a", Scope: ScopeRead}}},
+		{name: "missing token", tokens: []Token{{Name: "a", Scope: ScopeRead}}},
+		{name: "missing scope", tokens: []Token{{Name: "a", Token: "a"}}},
+		{name: "duplicate", tokens: []Token{{Name: "a", Token: "a", Scope: ScopeRead}, {Name: "a", Token: "b", Scope: ScopeRead}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAuthenticator("", tt.tokens, nil); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestAuthenticatorClientCertificateSubject(t *testing.T) {
+	a, err := NewAuthenticator("", []Token{
+		{Name: "ci", Subject: "ci-runner", Scope: ScopeOperate},
+		{Name: "dash", Subject: "CN=dashboard,O=Gastown", Scope: ScopeRead},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	withCert := func(subject pkix.Name) *http.Request {
+		req := httptest.NewRequest("GET", "https://localhost:8080/ws", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: subject}}}}
+		return req
+	}
+
+	if id, ok := a.Authenticate(withCert(pkix.Name{CommonName: "ci-runner"})); !ok || id.Name != "ci" || id.Scope != ScopeOperate {
+		t.Fatalf("CN match = %+v, %v", id, ok)
+	}
+	if id, ok := a.Authenticate(withCert(pkix.Name{CommonName: "dashboard", Organization: []string{"Gastown"}})); !ok || id.Name != "dash" {
+		t.Fatalf("DN match = %+v, %v", id, ok)
+	}
+	if _, ok := a.Authenticate(withCert(pkix.Name{CommonName: "stranger"})); ok {
+		t.Fatal("unmapped certificate must not authenticate")
+	}
+	// Subject-only identities have no bearer token to match.
+	if _, ok := a.Authenticate(httptest.NewRequest("GET", "http://localhost:8080/ws", nil)); ok {
+		t.Fatal("request without credentials must not authenticate")
+	}
+}
+
+func TestAuthenticatorUnixPeer(t *testing.T) {
+	a, err := NewAuthenticator("", []Token{
+		{Name: "editor", Peer: "uid:1001", Scope: ScopeOperate},
+		{Name: "staff", Peer: "gid:50", Scope: ScopeRead},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	a.uid = 1000
+
+	withPeer := func(cred PeerCred) *http.Request {
+		req := httptest.NewRequest("GET", "http://unix/ws", nil)
+		return req.WithContext(WithPeer(req.Context(), cred))
+	}
+
+	tests := []struct {
+		cred      PeerCred
+		wantScope Scope
+		wantOK    bool
+	}{
+		{cred: PeerCred{UID: 1000, GID: 1000}, wantScope: ScopeAdmin, wantOK: true},
+		{cred: PeerCred{UID: 1001, GID: 50}, wantScope: ScopeOperate, wantOK: true}, // uid rule wins
+		{cred: PeerCred{UID: 1002, GID: 50}, wantScope: ScopeRead, wantOK: true},
+		{cred: PeerCred{UID: 1003, GID: 1003}},
+	}
+	for _, tt := range tests {
+		id, ok := a.Authenticate(withPeer(tt.cred))
+		if ok != tt.wantOK || id.Scope != tt.wantScope {
+			t.Fatalf("Authenticate(%+v) = %+v, %v; want %s, %v", tt.cred, id, ok, tt.wantScope, tt.wantOK)
+		}
+	}
+
+	if _, err := NewAuthenticator("", []Token{{Name: "x", Peer: "user:bob", Scope: ScopeRead}}, nil); err == nil {
+		t.Fatal("expected error for invalid peer rule")
+	}
+}
@@ -0,0 +1,118 @@
+// Package certs provides TLS serving configuration with certificate reload
+// and optional client-certificate verification.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval limits how often the certificate files are stat'ed.
+const checkInterval = time.Second
+
+// Reloader serves a certificate/key pair and reloads it when either file's
+// modification time changes, so renewed certificates (e.g. from certbot) are
+// picked up without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+	checked time.Time
+}
+
+// NewReloader loads the initial certificate/key pair.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. If the files changed
+// but cannot be loaded (e.g. mid-rename), the previous certificate is kept.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.Sub(r.checked) >= checkInterval {
+		r.checked = now
+		certMod, keyMod, err := r.modTimes()
+		if err != nil {
+			log.Printf("tls: %v (keeping current certificate)", err)
+		} else if !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod) {
+			if err := r.load(certMod, keyMod); err != nil {
+				log.Printf("tls: reload: %v (keeping current certificate)", err)
+			} else {
+				log.Printf("tls: reloaded certificate from %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *Reloader) modTimes() (certMod, keyMod time.Time, err error) {
+	ci, err := os.Stat(r.certFile)
+	if err != nil {
+		return certMod, keyMod, fmt.Errorf("stat tls cert: %w", err)
+	}
+	ki, err := os.Stat(r.keyFile)
+	if err != nil {
+		return certMod, keyMod, fmt.Errorf("stat tls key: %w", err)
+	}
+	return ci.ModTime(), ki.ModTime(), nil
+}
+
+// load reads the pair; the caller holds r.mu (or owns r exclusively).
+func (r *Reloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls key pair: %w", err)
+	}
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	return nil
+}
+
+// LoadCertPool reads a PEM bundle of CA certificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA bundle %s: no PEM certificates found", path)
+	}
+	return pool, nil
+}
+
+// ServerConfig builds a TLS server configuration. If clientCAs is non-nil,
+// client certificates are requested and, when presented, must chain to one of
+// them; clients without a certificate can still use bearer tokens.
+func ServerConfig(r *Reloader, clientCAs *x509.CertPool) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if clientCAs != nil {
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
@@ -0,0 +1,117 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePair writes a self-signed certificate for cn and its key.
+func writePair(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func leafCN(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderPicksUpChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile, "first")
+
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if cn := leafCN(t, r); cn != "first" {
+		t.Fatalf("CN = %q, want first", cn)
+	}
+
+	writePair(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.checked = time.Time{} // skip the stat throttle
+	if cn := leafCN(t, r); cn != "second" {
+		t.Fatalf("CN after reload = %q, want second", cn)
+	}
+}
+
+func TestReloaderKeepsCertificateOnBadReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile, "good")
+
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if err := os.WriteFile(certFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	r.checked = time.Time{}
+	if cn := leafCN(t, r); cn != "good" {
+		t.Fatalf("CN = %q, want previous certificate", cn)
+	}
+}
+
+func TestLoadCertPoolRejectsEmptyBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertPool(path); err == nil {
+		t.Fatal("expected error for bundle without certificates")
+	}
+}
@@ -0,0 +1,150 @@
+package history
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+// maxPendingLine caps how many raw bytes are buffered waiting for a newline.
+// Full-screen TUIs redraw with cursor movement rather than newlines, so without
+// a cap a single "line" could grow without bound.
+const maxPendingLine = 16 * 1024
+
+// Recorder subscribes to pipe-pane output for tracked agents and appends the
+// ANSI-stripped lines to a Store. It also applies the store's retention policy
+// periodically.
+type Recorder struct {
+	store   *Store
+	pipeMgr *tmux.PipePaneManager
+	mu      sync.Mutex
+	tracks  map[string]<-chan []byte // agent -> pipe-pane subscription
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+}
+
+// NewRecorder creates a recorder that writes to store.
+func NewRecorder(store *Store, pipeMgr *tmux.PipePaneManager) *Recorder {
+	return &Recorder{
+		store:   store,
+		pipeMgr: pipeMgr,
+		tracks:  make(map[string]<-chan []byte),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start runs retention pruning immediately and then every interval.
+func (r *Recorder) Start(interval time.Duration) {
+	if err := r.store.Prune(time.Now()); err != nil {
+		log.Printf("history: prune: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				if err := r.store.Prune(time.Now()); err != nil {
+					log.Printf("history: prune: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Track begins recording output for an agent. Tracking an agent twice is a no-op.
+func (r *Recorder) Track(agent string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tracks[agent]; ok {
+		return
+	}
+
+	ch, err := r.pipeMgr.Subscribe(agent)
+	if err != nil {
+		log.Printf("history: track %s: %v", agent, err)
+		return
+	}
+	r.tracks[agent] = ch
+	log.Printf("history: recording %s", agent)
+
+	r.wg.Add(1)
+	go r.record(agent, ch)
+}
+
+// Untrack stops recording output for an agent. Its transcript is kept.
+func (r *Recorder) Untrack(agent string) {
+	r.mu.Lock()
+	ch, ok := r.tracks[agent]
+	delete(r.tracks, agent)
+	r.mu.Unlock()
+
+	if ok {
+		r.pipeMgr.Unsubscribe(agent, ch)
+	}
+}
+
+// Stop untracks all agents, waits for pending lines to be written, and closes the store.
+func (r *Recorder) Stop() {
+	close(r.stopCh)
+
+	r.mu.Lock()
+	agents := make([]string, 0, len(r.tracks))
+	for agent := range r.tracks {
+		agents = append(agents, agent)
+	}
+	r.mu.Unlock()
+
+	for _, agent := range agents {
+		r.Untrack(agent)
+	}
+	r.wg.Wait()
+
+	if err := r.store.Close(); err != nil {
+		log.Printf("history: close store: %v", err)
+	}
+}
+
+// record splits raw output into lines and appends them until ch is closed.
+func (r *Recorder) record(agent string, ch <-chan []byte) {
+	defer r.wg.Done()
+
+	var pending []byte
+	for chunk := range ch {
+		now := time.Now()
+		pending = append(pending, chunk...)
+		for {
+			idx := bytes.IndexByte(pending, '\n')
+			if idx < 0 {
+				break
+			}
+			r.appendLine(agent, now, pending[:idx])
+			pending = pending[idx+1:]
+		}
+		if len(pending) > maxPendingLine {
+			r.appendLine(agent, now, pending)
+			pending = nil
+		}
+	}
+	if len(pending) > 0 {
+		r.appendLine(agent, time.Now(), pending)
+	}
+}
+
+func (r *Recorder) appendLine(agent string, t time.Time, raw []byte) {
+	line := ansi.Strip(string(raw))
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if err := r.store.Append(agent, t, line); err != nil {
+		log.Printf("history: append %s: %v", agent, err)
+	}
+}
@@ -0,0 +1,443 @@
+// Package history provides a persistent, searchable transcript of agent output.
+// Transcripts are ANSI-stripped, append-only, and survive session death.
+package history
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentDateLayout = "2006-01-02"
+	segmentSuffix     = ".log"
+	lineTimeLayout    = time.RFC3339Nano
+)
+
+// Retention bounds how much transcript data is kept per agent.
+// Zero values disable the corresponding limit.
+type Retention struct {
+	MaxAge   time.Duration
+	MaxBytes int64
+}
+
+// Query describes a transcript search.
+type Query struct {
+	Text    string    // case-insensitive substring to match (required)
+	Agent   string    // agent name or glob pattern (optional)
+	Since   time.Time // only lines recorded at or after this time (optional)
+	Context int       // lines of context before and after each match
+	Limit   int       // maximum number of matches (newest first)
+
+	// Visible, if set, restricts the search to agents it returns true for.
+	Visible func(agent string) bool
+}
+
+// Match is a single transcript line that matched a Query.
+type Match struct {
+	Agent  string    `json:"agent"`
+	Time   time.Time `json:"time"`
+	Line   string    `json:"line"`
+	Before []string  `json:"before"`
+	After  []string  `json:"after"`
+}
+
+// Store writes one directory per agent with one segment file per UTC day.
+// Each line is stored as "<RFC3339Nano timestamp>\t<text>".
+type Store struct {
+	dir       string
+	retention Retention
+	mu        sync.Mutex
+	segments  map[string]*segment // agent -> currently open segment
+}
+
+type segment struct {
+	day  string
+	file *os.File
+}
+
+// NewStore creates a transcript store rooted at dir.
+func NewStore(dir string, retention Retention) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	return &Store{
+		dir:       dir,
+		retention: retention,
+		segments:  make(map[string]*segment),
+	}, nil
+}
+
+// Append records a single transcript line for an agent.
+func (s *Store) Append(agent string, t time.Time, line string) error {
+	dirName, err := agentDirName(agent)
+	if err != nil {
+		return err
+	}
+
+	t = t.UTC()
+	day := t.Format(segmentDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, ok := s.segments[agent]
+	if !ok || seg.day != day {
+		if ok {
+			if err := seg.file.Close(); err != nil {
+				log.Printf("history: close segment %s/%s: %v", agent, seg.day, err)
+			}
+		}
+		agentDir := filepath.Join(s.dir, dirName)
+		if err := os.MkdirAll(agentDir, 0o755); err != nil {
+			return fmt.Errorf("create agent history dir: %w", err)
+		}
+		f, err := os.OpenFile(filepath.Join(agentDir, day+segmentSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			delete(s.segments, agent)
+			return fmt.Errorf("open history segment: %w", err)
+		}
+		seg = &segment{day: day, file: f}
+		s.segments[agent] = seg
+	}
+
+	if _, err := fmt.Fprintf(seg.file, "%s\t%s\n", t.Format(lineTimeLayout), line); err != nil {
+		return fmt.Errorf("write history line: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes all open segment files.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for agent, seg := range s.segments {
+		if err := seg.file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close history segment %s: %w", agent, err)
+		}
+		delete(s.segments, agent)
+	}
+	return firstErr
+}
+
+// Agents returns the names of all agents with recorded transcripts,
+// including agents whose sessions no longer exist.
+func (s *Store) Agents() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Search scans transcripts for lines containing q.Text and returns the newest
+// q.Limit matches with surrounding context. Segments are scanned newest day
+// first, and only the newest q.Limit matches are kept while scanning.
+func (s *Store) Search(q Query) ([]Match, error) {
+	needle := strings.ToLower(q.Text)
+	if needle == "" {
+		return nil, fmt.Errorf("search text required")
+	}
+	if q.Agent != "" {
+		if _, err := path.Match(q.Agent, ""); err != nil {
+			return nil, fmt.Errorf("invalid agent pattern: %w", err)
+		}
+	}
+
+	agentNames, err := s.Agents()
+	if err != nil {
+		return nil, err
+	}
+
+	type daySegment struct{ agent, day string }
+	var segments []daySegment
+	for _, agent := range agentNames {
+		if q.Agent != "" {
+			if ok, _ := path.Match(q.Agent, agent); !ok {
+				continue
+			}
+		}
+		if q.Visible != nil && !q.Visible(agent) {
+			continue
+		}
+		days, err := s.segmentDays(agent)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // pruned since it was listed
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range days {
+			if !q.Since.IsZero() && day < q.Since.UTC().Format(segmentDateLayout) {
+				continue
+			}
+			segments = append(segments, daySegment{agent, day})
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].day > segments[j].day
+	})
+
+	kept := &newestMatches{limit: q.Limit}
+	for _, seg := range segments {
+		// Every line of a segment is older than the end of its day; once
+		// the oldest kept match is not, no older segment can displace it.
+		if kept.full() {
+			dayStart, _ := time.Parse(segmentDateLayout, seg.day)
+			if !kept.items[0].Time.Before(dayStart.AddDate(0, 0, 1)) {
+				break
+			}
+		}
+		if err := s.searchSegment(seg.agent, seg.day, needle, q, kept); err != nil {
+			return nil, err
+		}
+	}
+
+	matches := make([]Match, len(kept.items))
+	for i, m := range kept.items {
+		matches[i] = *m
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].Time.Equal(matches[j].Time) {
+			return matches[i].Time.After(matches[j].Time)
+		}
+		return matches[i].Agent < matches[j].Agent
+	})
+	return matches, nil
+}
+
+// newestMatches keeps the newest limit matches offered to it, as a min-heap
+// by time; a zero limit keeps every match.
+type newestMatches struct {
+	limit int
+	items []*Match
+}
+
+func (h *newestMatches) Len() int           { return len(h.items) }
+func (h *newestMatches) Less(i, j int) bool { return h.items[i].Time.Before(h.items[j].Time) }
+func (h *newestMatches) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *newestMatches) Push(x any)         { h.items = append(h.items, x.(*Match)) }
+
+func (h *newestMatches) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func (h *newestMatches) full() bool {
+	return h.limit > 0 && len(h.items) >= h.limit
+}
+
+// add offers a match, evicting the oldest kept one if it is newer. It
+// reports whether m was kept.
+func (h *newestMatches) add(m *Match) bool {
+	if !h.full() {
+		heap.Push(h, m)
+		return true
+	}
+	if !m.Time.After(h.items[0].Time) {
+		return false
+	}
+	h.items[0] = m
+	heap.Fix(h, 0)
+	return true
+}
+
+// searchSegment scans one day's segment for an agent, offering its matches
+// to kept. A segment pruned since it was listed is skipped.
+func (s *Store) searchSegment(agent, day, needle string, q Query, kept *newestMatches) error {
+	f, err := os.Open(filepath.Join(s.dir, agent, day+segmentSuffix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open history segment: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("history: close segment %s/%s: %v", agent, day, err)
+		}
+	}()
+
+	var (
+		before []string // rolling window of previous lines
+		open   []*Match // kept matches still collecting After lines
+	)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		t, text, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		// Feed trailing context to earlier matches
+		stillOpen := open[:0]
+		for _, m := range open {
+			m.After = append(m.After, text)
+			if len(m.After) < q.Context {
+				stillOpen = append(stillOpen, m)
+			}
+		}
+		open = stillOpen
+
+		if (q.Since.IsZero() || !t.Before(q.Since)) && strings.Contains(strings.ToLower(text), needle) {
+			m := &Match{
+				Agent:  agent,
+				Time:   t,
+				Line:   text,
+				Before: append([]string{}, before...),
+				After:  []string{},
+			}
+			if kept.add(m) && q.Context > 0 {
+				open = append(open, m)
+			}
+		}
+
+		if q.Context > 0 {
+			before = append(before, text)
+			if len(before) > q.Context {
+				before = before[1:]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return fmt.Errorf("scan history segment: %w", err)
+	}
+	return nil
+}
+
+// Prune applies the retention policy, deleting whole segments that are older
+// than MaxAge or that push an agent's transcript over MaxBytes.
+// The newest segment of each agent is never removed by the size limit.
+func (s *Store) Prune(now time.Time) error {
+	agentNames, err := s.Agents()
+	if err != nil {
+		return err
+	}
+
+	cutoff := ""
+	if s.retention.MaxAge > 0 {
+		cutoff = now.UTC().Add(-s.retention.MaxAge).Format(segmentDateLayout)
+	}
+
+	for _, agent := range agentNames {
+		days, err := s.segmentDays(agent)
+		if err != nil {
+			return err
+		}
+
+		type sized struct {
+			day  string
+			size int64
+		}
+		var kept []sized
+		var total int64
+		for _, day := range days {
+			p := filepath.Join(s.dir, agent, day+segmentSuffix)
+			if cutoff != "" && day < cutoff {
+				s.removeSegment(agent, day, p)
+				continue
+			}
+			info, err := os.Stat(p)
+			if err != nil {
+				return fmt.Errorf("stat history segment: %w", err)
+			}
+			kept = append(kept, sized{day: day, size: info.Size()})
+			total += info.Size()
+		}
+
+		if s.retention.MaxBytes > 0 {
+			for len(kept) > 1 && total > s.retention.MaxBytes {
+				s.removeSegment(agent, kept[0].day, filepath.Join(s.dir, agent, kept[0].day+segmentSuffix))
+				total -= kept[0].size
+				kept = kept[1:]
+			}
+		}
+
+		if len(kept) == 0 {
+			if err := os.Remove(filepath.Join(s.dir, agent)); err != nil {
+				log.Printf("history: remove empty dir %s: %v", agent, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) removeSegment(agent, day, p string) {
+	s.mu.Lock()
+	if seg, ok := s.segments[agent]; ok && seg.day == day {
+		if err := seg.file.Close(); err != nil {
+			log.Printf("history: close segment %s/%s: %v", agent, day, err)
+		}
+		delete(s.segments, agent)
+	}
+	s.mu.Unlock()
+
+	if err := os.Remove(p); err != nil {
+		log.Printf("history: prune %s: %v", p, err)
+		return
+	}
+	log.Printf("history: pruned %s/%s", agent, day)
+}
+
+// segmentDays returns the day stamps of an agent's segments, oldest first.
+func (s *Store) segmentDays(agent string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, agent))
+	if err != nil {
+		return nil, fmt.Errorf("read agent history dir: %w", err)
+	}
+	var days []string
+	for _, e := range entries {
+		day, ok := strings.CutSuffix(e.Name(), segmentSuffix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(segmentDateLayout, day); err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+func parseLine(raw string) (time.Time, string, bool) {
+	stamp, text, ok := strings.Cut(raw, "\t")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	t, err := time.Parse(lineTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return t, text, true
+}
+
+// agentDirName validates that an agent name is safe to use as a directory name.
+func agentDirName(agent string) (string, error) {
+	if agent == "" || agent == "." || agent == ".." || strings.ContainsAny(agent, `/\`) {
+		return "", fmt.Errorf("invalid agent name for history: %q", agent)
+	}
+	return agent, nil
+}
@@ -0,0 +1,212 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, retention Retention) *Store {
+	t.Helper()
+	s, err := NewStore(t.TempDir(), retention)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return s
+}
+
+func mustAppend(t *testing.T, s *Store, agent string, ts time.Time, line string) {
+	t.Helper()
+	if err := s.Append(agent, ts, line); err != nil {
+		t.Fatalf("Append(%q) error = %v", line, err)
+	}
+}
+
+func TestSearchReturnsMatchesWithContext(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mustAppend(t, s, "gt-myrig-crew-bob", base, "running tests")
+	mustAppend(t, s, "gt-myrig-crew-bob", base.Add(time.Second), "panic: runtime error")
+	mustAppend(t, s, "gt-myrig-crew-bob", base.Add(2*time.Second), "goroutine 1 [running]:")
+	mustAppend(t, s, "gt-myrig-crew-bob", base.Add(3*time.Second), "exit status 2")
+	mustAppend(t, s, "hq-mayor", base.Add(4*time.Second), "all good")
+
+	matches, err := s.Search(Query{Text: "PANIC", Context: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("len(matches) = %d, want 1", len(matches))
+	}
+	m := matches[0]
+	if m.Agent != "gt-myrig-crew-bob" || m.Line != "panic: runtime error" {
+		t.Fatalf("match = %+v, unexpected agent/line", m)
+	}
+	if len(m.Before) != 1 || m.Before[0] != "running tests" {
+		t.Fatalf("Before = %q, want [running tests]", m.Before)
+	}
+	if len(m.After) != 1 || m.After[0] != "goroutine 1 [running]:" {
+		t.Fatalf("After = %q, want [goroutine 1 [running]:]", m.After)
+	}
+	if !m.Time.Equal(base.Add(time.Second)) {
+		t.Fatalf("Time = %v, want %v", m.Time, base.Add(time.Second))
+	}
+}
+
+func TestSearchFiltersByAgentAndSince(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+
+	mustAppend(t, s, "gt-a-crew-x", day1, "error one")
+	mustAppend(t, s, "gt-a-crew-x", day2, "error two")
+	mustAppend(t, s, "gt-b-witness", day2, "error three")
+
+	matches, err := s.Search(Query{Text: "error", Since: day1.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("len(matches) = %d, want 2", len(matches))
+	}
+
+	matches, err = s.Search(Query{Text: "error", Agent: "gt-a-*"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("len(matches) = %d, want 2", len(matches))
+	}
+	if matches[0].Line != "error two" {
+		t.Fatalf("matches[0].Line = %q, want newest first", matches[0].Line)
+	}
+
+	matches, err = s.Search(Query{Text: "error", Limit: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("len(matches) = %d, want 1", len(matches))
+	}
+
+	// Invisible agents are skipped before the limit is applied.
+	matches, err = s.Search(Query{Text: "error", Limit: 1, Visible: func(agent string) bool { return agent == "gt-b-witness" }})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Agent != "gt-b-witness" {
+		t.Fatalf("matches = %+v, want only gt-b-witness", matches)
+	}
+}
+
+func TestSearchKeepsNewestMatchesOverLimit(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	base := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	// Three days of matches interleaved across two agents, with context
+	// lines after each.
+	for i := range 30 {
+		agent := []string{"gt-a-crew-x", "gt-b-witness"}[i%2]
+		ts := base.Add(time.Duration(i) * 2 * time.Hour)
+		mustAppend(t, s, agent, ts, fmt.Sprintf("error %d", i))
+		mustAppend(t, s, agent, ts.Add(time.Second), fmt.Sprintf("after %d", i))
+	}
+
+	matches, err := s.Search(Query{Text: "error", Limit: 5, Context: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 5 {
+		t.Fatalf("len(matches) = %d, want 5", len(matches))
+	}
+	for i, m := range matches {
+		n := 29 - i
+		if m.Line != fmt.Sprintf("error %d", n) || len(m.After) != 1 || m.After[0] != fmt.Sprintf("after %d", n) {
+			t.Errorf("matches[%d] = %q after %q, want error %d", i, m.Line, m.After, n)
+		}
+	}
+}
+
+func TestSearchSkipsPrunedSegment(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	kept := &newestMatches{limit: 10}
+	if err := s.searchSegment("gt-a-crew-x", "2026-03-01", "error", Query{Text: "error"}, kept); err != nil {
+		t.Fatalf("searchSegment() error = %v", err)
+	}
+	if len(kept.items) != 0 {
+		t.Fatalf("matches = %d, want 0", len(kept.items))
+	}
+}
+
+func TestSearchRequiresText(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	if _, err := s.Search(Query{}); err == nil {
+		t.Fatal("expected error for empty query text")
+	}
+}
+
+func TestAppendRejectsUnsafeAgentName(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	for _, name := range []string{"", "..", "a/b"} {
+		if err := s.Append(name, time.Now(), "x"); err == nil {
+			t.Fatalf("Append(%q) expected error", name)
+		}
+	}
+}
+
+func TestPruneByAge(t *testing.T) {
+	s := newTestStore(t, Retention{MaxAge: 48 * time.Hour})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	mustAppend(t, s, "hq-mayor", now.Add(-5*24*time.Hour), "old")
+	mustAppend(t, s, "hq-mayor", now, "new")
+	mustAppend(t, s, "hq-deacon", now.Add(-5*24*time.Hour), "gone")
+
+	if err := s.Prune(now); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	agents, err := s.Agents()
+	if err != nil {
+		t.Fatalf("Agents() error = %v", err)
+	}
+	if len(agents) != 1 || agents[0] != "hq-mayor" {
+		t.Fatalf("Agents() = %q, want [hq-mayor]", agents)
+	}
+	days, err := s.segmentDays("hq-mayor")
+	if err != nil {
+		t.Fatalf("segmentDays() error = %v", err)
+	}
+	if len(days) != 1 || days[0] != "2026-03-10" {
+		t.Fatalf("days = %q, want [2026-03-10]", days)
+	}
+}
+
+func TestPruneBySizeKeepsNewestSegment(t *testing.T) {
+	s := newTestStore(t, Retention{MaxBytes: 1})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	mustAppend(t, s, "hq-mayor", now.Add(-2*24*time.Hour), "older")
+	mustAppend(t, s, "hq-mayor", now.Add(-24*time.Hour), "old")
+	mustAppend(t, s, "hq-mayor", now, "new")
+
+	if err := s.Prune(now); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "hq-mayor"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "2026-03-10.log" {
+		t.Fatalf("remaining segments = %v, want only newest", entries)
+	}
+}
@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RedactedValue replaces secret environment values in responses.
+const RedactedValue = "[redacted]"
+
+// secretEnvPatterns match (upper-cased) names of environment variables whose
+// values are never returned.
+var secretEnvPatterns = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*CREDENTIAL*",
+	"*PRIVATE*",
+	"*API_KEY*",
+	"*APIKEY*",
+	"*_KEY",
+	"*AUTH*",
+	"*COOKIE*",
+}
+
+// IsSecretEnv reports whether an environment variable's value must be
+// redacted.
+func IsSecretEnv(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, pattern := range secretEnvPatterns {
+		if ok, _ := path.Match(pattern, upper); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEnvVar validates a caller-supplied session environment variable.
+// GT_* variables identify the agent to the registry and are the adapter's.
+func checkEnvVar(key, value string) error {
+	if !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	if strings.HasPrefix(key, "GT_") {
+		return fmt.Errorf("%s is set by the adapter", key)
+	}
+	if hasControl(value) {
+		return fmt.Errorf("environment variable %s contains control characters", key)
+	}
+	return nil
+}
+
+// Env returns an agent session's environment with secret values replaced by
+// RedactedValue, and the sorted names of the redacted variables.
+func (m *Manager) Env(name string) (map[string]string, []string, error) {
+	env, err := m.ctrl.ShowEnvironmentAll(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("show-environment %s: %w", name, err)
+	}
+	redacted := []string{}
+	for key := range env {
+		if IsSecretEnv(key) {
+			env[key] = RedactedValue
+			redacted = append(redacted, key)
+		}
+	}
+	sort.Strings(redacted)
+	return env, redacted, nil
+}
+
+// SetEnv sets a variable in an agent session's environment. Running
+// processes keep their environment; processes started later (restarts,
+// new panes) see the change.
+func (m *Manager) SetEnv(name, key, value string) error {
+	if err := checkEnvVar(key, value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEnv, err)
+	}
+	if err := m.ctrl.SetEnvironment(name, key, value); err != nil {
+		return fmt.Errorf("set-environment %s: %w", name, err)
+	}
+	return nil
+}
+
+// UnsetEnv removes a variable from an agent session's environment.
+func (m *Manager) UnsetEnv(name, key string) error {
+	if err := checkEnvVar(key, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEnv, err)
+	}
+	if err := m.ctrl.UnsetEnvironment(name, key); err != nil {
+		return fmt.Errorf("set-environment -u %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/nudge"
+)
+
+// DefaultKillGrace is how long Kill waits after SIGTERM (and after a graceful
+// exit command) before escalating.
+const DefaultKillGrace = 5 * time.Second
+
+// MaxKillGrace caps a caller-supplied grace period.
+const MaxKillGrace = time.Minute
+
+// runtimeExitCommands are the commands that make each runtime's CLI quit.
+// Runtimes without one skip the graceful phase.
+var runtimeExitCommands = map[string]string{
+	"claude":   "/exit",
+	"gemini":   "/quit",
+	"codex":    "/quit",
+	"opencode": "/exit",
+}
+
+// KillOptions controls how Kill stops an agent.
+type KillOptions struct {
+	Graceful bool          // send the runtime's exit command first
+	Grace    time.Duration // wait per phase; DefaultKillGrace if zero
+}
+
+// KillReport describes what Kill did.
+type KillReport struct {
+	Exited  bool  `json:"exited,omitempty"` // the agent quit on its exit command
+	SIGTERM []int `json:"sigterm"`          // PIDs sent SIGTERM
+	SIGKILL []int `json:"sigkill"`          // PIDs still alive after the grace period
+}
+
+// Kill stops a session and every process under it: optionally the runtime's
+// exit command, then SIGTERM to the process trees of all panes (orphaned
+// process-group members and descendants deepest-first, pane processes last),
+// SIGKILL to survivors after the grace period, and finally kill-session.
+// kill-session alone sends SIGHUP, which many agent CLIs ignore.
+func (m *Manager) Kill(ctx context.Context, name string, opts KillOptions) (KillReport, error) {
+	report := KillReport{SIGTERM: []int{}, SIGKILL: []int{}}
+	grace := opts.Grace
+	if grace <= 0 {
+		grace = DefaultKillGrace
+	}
+
+	pids, err := m.ctrl.PanePIDs(name)
+	if err != nil {
+		return report, fmt.Errorf("list panes of %s: %w", name, err)
+	}
+	// A deliberate stop is not a crash: let the panes close as they exit,
+	// and cancel any pending supervised restart.
+	m.forget(name)
+	if err := m.ctrl.UnwatchPaneDied(name, m.registry.Target(name)); err != nil {
+		log.Printf("kill %s: remove pane-died hook: %v", name, err)
+	}
+
+	if agent, ok := m.registry.GetAgent(name); ok && opts.Graceful {
+		if m.exitGracefully(ctx, agent, pids, grace) {
+			report.Exited = true
+			return report, m.killSession(name)
+		}
+	}
+
+	var procs []int
+	for _, pid := range pids {
+		procs = append(procs, processTree(pid)...)
+	}
+	sigterm, sigkill := terminate(ctx, procs, grace)
+	log.Printf("kill %s: SIGTERM %v, SIGKILL %v", name, sigterm, sigkill)
+	report.SIGTERM = append(report.SIGTERM, sigterm...)
+	report.SIGKILL = append(report.SIGKILL, sigkill...)
+	return report, m.killSession(name)
+}
+
+// exitGracefully sends the agent's exit command and reports whether its pane
+// processes exited within grace.
+func (m *Manager) exitGracefully(ctx context.Context, agent agents.Agent, pids []int, grace time.Duration) bool {
+	command, ok := runtimeExitCommands[agent.Runtime]
+	if !ok {
+		return false
+	}
+	lock := nudge.GetLock(agent.Name)
+	lock.Lock()
+	err := nudge.Session(m.ctrl, agent, command)
+	lock.Unlock()
+	if err != nil {
+		log.Printf("kill %s: send %s: %v", agent.Name, command, err)
+		return false
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, grace)
+	defer cancel()
+	return len(waitExit(waitCtx, pids)) == 0
+}
+
+// killSession removes the session unless it already went away with its
+// processes.
+func (m *Manager) killSession(name string) error {
+	if exists, _ := m.ctrl.HasSession(name); !exists {
+		return nil
+	}
+	return m.ctrl.KillSession(name)
+}
+
+// RestartResult describes a restarted agent.
+type RestartResult struct {
+	Agent   agents.Agent `json:"agent"`
+	PrevPID int          `json:"prevPid"`
+	PID     int          `json:"pid"`
+}
+
+// Restart respawns an agent's pane with its original command, keeping the
+// session name and environment, and waits for the new agent process.
+// Processes of the old tree that survive respawn-pane's SIGHUP are
+// terminated.
+func (m *Manager) Restart(ctx context.Context, name string) (RestartResult, error) {
+	prevPID, ok := m.registry.PanePID(name)
+	if !ok {
+		return RestartResult{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	old := processTree(prevPID)
+
+	log.Printf("restart %s: respawning pane (pid %d)", name, prevPID)
+	m.registry.ExpectRestart(name)
+	if err := m.ctrl.RespawnPane(m.registry.Target(name)); err != nil {
+		return RestartResult{}, fmt.Errorf("respawn-pane %s: %w", name, err)
+	}
+	if current, err := m.ctrl.PanePIDs(name); err == nil {
+		old = slices.DeleteFunc(old, func(pid int) bool { return slices.Contains(current, pid) })
+	}
+	if sigterm, sigkill := terminate(ctx, old, DefaultKillGrace); len(sigterm) > 0 {
+		log.Printf("restart %s: old processes SIGTERM %v, SIGKILL %v", name, sigterm, sigkill)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.spawnTimeout)
+	defer cancel()
+	agent, pid, err := m.waitForAgent(ctx, name, prevPID)
+	return RestartResult{Agent: agent, PrevPID: prevPID, PID: pid}, err
+}
@@ -0,0 +1,125 @@
+// Package lifecycle creates and manages gastown agent sessions.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+// DefaultSpawnTimeout bounds how long Spawn waits for the agent process.
+const DefaultSpawnTimeout = 30 * time.Second
+
+// spawnPollInterval is how often Spawn rescans while waiting for the agent.
+const spawnPollInterval = 250 * time.Millisecond
+
+var (
+	ErrInvalidSpec = errors.New("invalid spawn request")
+	ErrExists      = errors.New("session already exists")
+	ErrNotStarted  = errors.New("agent did not start")
+	ErrNotFound    = errors.New("agent not found")
+
+	ErrInvalidPolicy = errors.New("invalid restart policy")
+	ErrInvalidEnv    = errors.New("invalid environment variable")
+)
+
+// Manager creates, stops and supervises agent sessions through tmux control
+// mode.
+type Manager struct {
+	ctrl         *tmux.ControlMode
+	registry     *agents.Registry
+	gtDir        string
+	spawnTimeout time.Duration
+
+	mu         sync.Mutex
+	policies   RestartPolicies
+	supervised map[string]*supervision
+}
+
+// New creates a lifecycle Manager for the town at gtDir that restarts agents
+// according to policies.
+func New(ctrl *tmux.ControlMode, registry *agents.Registry, gtDir string, policies RestartPolicies) *Manager {
+	return &Manager{
+		ctrl:         ctrl,
+		registry:     registry,
+		gtDir:        gtDir,
+		spawnTimeout: DefaultSpawnTimeout,
+		policies:     policies,
+		supervised:   make(map[string]*supervision),
+	}
+}
+
+// Spawn creates a session for spec and waits until the registry reports its
+// agent alive. A session whose agent never comes up is left in place for
+// inspection; the registry ignores it.
+func (m *Manager) Spawn(ctx context.Context, spec Spec) (agents.Agent, error) {
+	name, err := spec.SessionName()
+	if err != nil {
+		return agents.Agent{}, err
+	}
+	if _, ok := runtimeCommands[spec.runtime()]; !ok {
+		return agents.Agent{}, invalidf("unknown runtime %q", spec.Runtime)
+	}
+	dir, err := spec.workDir(m.gtDir)
+	if err != nil {
+		return agents.Agent{}, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return agents.Agent{}, invalidf("working directory %s does not exist", dir)
+	}
+	env, err := spec.env()
+	if err != nil {
+		return agents.Agent{}, err
+	}
+	command, err := spec.command()
+	if err != nil {
+		return agents.Agent{}, err
+	}
+
+	if exists, _ := m.ctrl.HasSession(name); exists {
+		return agents.Agent{}, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	m.forget(name)
+	log.Printf("spawn %s: %s in %s", name, command, dir)
+	if err := m.ctrl.NewSession(name, dir, env, command); err != nil {
+		return agents.Agent{}, fmt.Errorf("new-session %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.spawnTimeout)
+	defer cancel()
+	agent, _, err := m.waitForAgent(ctx, name, 0)
+	return agent, err
+}
+
+// waitForAgent rescans until name is a live agent whose pane process is not
+// prevPID, its session exits or ctx is done. It returns the agent and its
+// pane process.
+func (m *Manager) waitForAgent(ctx context.Context, name string, prevPID int) (agents.Agent, int, error) {
+	ticker := time.NewTicker(spawnPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.registry.Refresh(); err != nil {
+			log.Printf("wait for %s: scan: %v", name, err)
+		}
+		if pid, ok := m.registry.PanePID(name); ok && pid != prevPID {
+			if agent, ok := m.registry.GetAgent(name); ok {
+				return agent, pid, nil
+			}
+		}
+		if exists, _ := m.ctrl.HasSession(name); !exists {
+			return agents.Agent{}, 0, fmt.Errorf("%w: session %s exited", ErrNotStarted, name)
+		}
+		select {
+		case <-ctx.Done():
+			return agents.Agent{}, 0, fmt.Errorf("%w: no live agent in session %s: %v", ErrNotStarted, name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
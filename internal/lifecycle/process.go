@@ -0,0 +1,132 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxTreeDepth bounds the descendant walk, as in agents.CheckDescendants.
+const maxTreeDepth = 10
+
+// processPollInterval is how often signalled processes are checked for exit.
+const processPollInterval = 100 * time.Millisecond
+
+// processTree returns the processes to signal for a pane process, in kill
+// order: members of the tree's process groups that were reparented away from
+// it (orphaned grandchildren), then descendants deepest-first, then root.
+func processTree(root int) []int {
+	tree := append(descendants(root, 0), root)
+
+	seen := make(map[int]bool, len(tree))
+	groups := make(map[int]bool)
+	self := syscall.Getpgrp()
+	for _, pid := range tree {
+		seen[pid] = true
+		if pgid, err := syscall.Getpgid(pid); err == nil && pgid != self {
+			groups[pgid] = true
+		}
+	}
+
+	var reparented []int
+	for pgid := range groups {
+		for _, pid := range pgrep("-g", strconv.Itoa(pgid)) {
+			if !seen[pid] && pid != os.Getpid() {
+				seen[pid] = true
+				reparented = append(reparented, pid)
+			}
+		}
+	}
+	slices.Sort(reparented)
+	return append(reparented, tree...)
+}
+
+// descendants returns pid's descendants, each child after its own children.
+func descendants(pid, depth int) []int {
+	if depth >= maxTreeDepth {
+		return nil
+	}
+	var out []int
+	for _, child := range pgrep("-P", strconv.Itoa(pid)) {
+		out = append(out, descendants(child, depth+1)...)
+		out = append(out, child)
+	}
+	return out
+}
+
+// pgrep returns the PIDs pgrep matches; none on error (pgrep exits 1 when
+// nothing matches).
+func pgrep(args ...string) []int {
+	out, err := exec.Command("pgrep", args...).Output()
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(out)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// signal sends sig to each pid and returns those it was delivered to.
+func signal(pids []int, sig syscall.Signal) []int {
+	var sent []int
+	for _, pid := range pids {
+		if pid <= 1 || pid == os.Getpid() {
+			continue
+		}
+		if err := syscall.Kill(pid, sig); err == nil {
+			sent = append(sent, pid)
+		}
+	}
+	return sent
+}
+
+// terminate sends SIGTERM to procs and SIGKILL to any still alive after
+// grace, returning the PIDs each signal reached.
+func terminate(ctx context.Context, procs []int, grace time.Duration) (sigterm, sigkill []int) {
+	sigterm = signal(procs, syscall.SIGTERM)
+	waitCtx, cancel := context.WithTimeout(ctx, grace)
+	survivors := waitExit(waitCtx, sigterm)
+	cancel()
+	return sigterm, signal(survivors, syscall.SIGKILL)
+}
+
+// waitExit waits until none of pids is alive or ctx is done, and returns the
+// survivors.
+func waitExit(ctx context.Context, pids []int) []int {
+	pids = slices.Clone(pids)
+	ticker := time.NewTicker(processPollInterval)
+	defer ticker.Stop()
+	for {
+		pids = slices.DeleteFunc(pids, func(pid int) bool { return !alive(pid) })
+		if len(pids) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return pids
+		case <-ticker.C:
+		}
+	}
+}
+
+// alive reports whether pid is running. Zombies awaiting reaping count as
+// exited.
+func alive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	out, err := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return false
+	}
+	return !strings.HasPrefix(strings.TrimSpace(string(out)), "Z")
+}
@@ -0,0 +1,52 @@
+package lifecycle
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcessTreeFindsReparentedGroupMembers(t *testing.T) {
+	// The subshell's sleep is orphaned (reparented away from sh) but stays in
+	// sh's process group; the second sleep is a plain child.
+	cmd := exec.Command("sh", "-c", "(sleep 30 &); sleep 30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	root := cmd.Process.Pid
+	t.Cleanup(func() {
+		syscall.Kill(-root, syscall.SIGKILL)
+		cmd.Wait()
+	})
+
+	var tree []int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if tree = processTree(root); len(tree) == 3 {
+			break
+		}
+	}
+	if len(tree) != 3 || tree[2] != root {
+		t.Fatalf("processTree(%d) = %v, want orphan, child, then root", root, tree)
+	}
+	if children := pgrep("-P", strconv.Itoa(root)); slices.Contains(children, tree[0]) || !slices.Contains(children, tree[1]) {
+		t.Fatalf("processTree(%d) = %v, want the orphan before sh's child %v", root, tree, children)
+	}
+
+	sent := signal(tree, syscall.SIGTERM)
+	if len(sent) != 3 {
+		t.Fatalf("signal() delivered to %v, want all of %v", sent, tree)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if survivors := waitExit(ctx, sent); len(survivors) > 0 {
+		t.Fatalf("waitExit() survivors = %v", survivors)
+	}
+	if !slices.Equal(sent, tree) {
+		t.Fatalf("waitExit() modified its argument: %v, want %v", sent, tree)
+	}
+}
@@ -0,0 +1,197 @@
+package lifecycle
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+)
+
+// Spec describes an agent session to spawn.
+type Spec struct {
+	Role    string            `json:"role"`
+	Rig     string            `json:"rig,omitempty"`
+	Name    string            `json:"name,omitempty"`    // crew member or polecat name
+	Runtime string            `json:"runtime,omitempty"` // agent preset; defaults to claude
+	WorkDir string            `json:"workDir,omitempty"` // relative to the town; defaults by role
+	Command string            `json:"command,omitempty"` // defaults to the runtime's CLI
+	Env     map[string]string `json:"env,omitempty"`     // extra session environment
+}
+
+// runtimeCommands maps agent presets to the command that starts them.
+var runtimeCommands = map[string]string{
+	"claude":   "claude",
+	"gemini":   "gemini",
+	"codex":    "codex",
+	"cursor":   "cursor-agent",
+	"auggie":   "auggie",
+	"amp":      "amp",
+	"opencode": "opencode",
+}
+
+// rigRoles are the rig-level roles with one session per rig.
+var rigRoles = map[string]bool{"witness": true, "refinery": true, "overseer": true}
+
+var (
+	segmentPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`) // roles and rigs: no dashes, they delimit session names
+	namePattern    = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+	envKeyPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+const maxNameLen = 64
+
+// SessionName returns the gastown session name for the spec: hq-ROLE for
+// town-level agents, gt-RIG-ROLE, gt-RIG-crew-NAME or gt-RIG-NAME (polecats)
+// for rig-level ones.
+func (s Spec) SessionName() (string, error) {
+	if !segmentPattern.MatchString(s.Role) {
+		return "", invalidf("role %q must be letters, digits or underscores", s.Role)
+	}
+	if s.Role == agents.RoleAdhoc {
+		return "", invalidf("role %s is reserved for agents outside gastown", s.Role)
+	}
+	if s.Rig != "" && !segmentPattern.MatchString(s.Rig) {
+		return "", invalidf("rig %q must be letters, digits or underscores", s.Rig)
+	}
+	if s.Name != "" && (!namePattern.MatchString(s.Name) || len(s.Name) > maxNameLen) {
+		return "", invalidf("name %q must be up to %d letters, digits, dashes or underscores", s.Name, maxNameLen)
+	}
+
+	var name string
+	switch {
+	case s.Role == "crew" || s.Role == "polecat":
+		if s.Rig == "" || s.Name == "" {
+			return "", invalidf("%s agents need a rig and a name", s.Role)
+		}
+		name = "gt-" + s.Rig + "-" + s.Name
+		if s.Role == "crew" {
+			name = "gt-" + s.Rig + "-crew-" + s.Name
+		}
+	case rigRoles[s.Role]:
+		if s.Rig == "" || s.Name != "" {
+			return "", invalidf("%s agents need a rig and no name", s.Role)
+		}
+		name = "gt-" + s.Rig + "-" + s.Role
+	case s.Role == "boot":
+		if s.Rig != "" || s.Name != "" {
+			return "", invalidf("boot takes no rig or name")
+		}
+		name = "gt-boot"
+	default:
+		if s.Rig != "" || s.Name != "" {
+			return "", invalidf("town-level role %s takes no rig or name", s.Role)
+		}
+		name = "hq-" + s.Role
+	}
+
+	// The registry must read the session back as the same agent.
+	if role, rig := agents.ParseSessionName(name); role != s.Role || rig != s.Rig {
+		return "", invalidf("session %s would be read back as role %s", name, role)
+	}
+	return name, nil
+}
+
+// workDir resolves the spec's working directory inside the town. By default
+// town-level agents run in the town root, crew in RIG/crew/NAME, polecats in
+// RIG/polecats/NAME and other rig roles in RIG/ROLE.
+func (s Spec) workDir(gtDir string) (string, error) {
+	dir := s.WorkDir
+	if dir == "" {
+		switch {
+		case s.Rig == "":
+			dir = "."
+		case s.Role == "crew":
+			dir = filepath.Join(s.Rig, "crew", s.Name)
+		case s.Role == "polecat":
+			dir = filepath.Join(s.Rig, "polecats", s.Name)
+		default:
+			dir = filepath.Join(s.Rig, s.Role)
+		}
+	}
+	if hasControl(dir) {
+		return "", invalidf("workDir contains control characters")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gtDir, dir)
+	}
+	dir = filepath.Clean(dir)
+	if gtDir != "" {
+		// The registry ignores sessions outside the town.
+		if rel, err := filepath.Rel(gtDir, dir); err != nil || !filepath.IsLocal(rel) {
+			return "", invalidf("workDir %s is outside the town %s", dir, gtDir)
+		}
+	}
+	return dir, nil
+}
+
+// command returns the command line that starts the agent.
+func (s Spec) command() (string, error) {
+	if s.Command != "" {
+		if hasControl(s.Command) {
+			return "", invalidf("command contains control characters")
+		}
+		return s.Command, nil
+	}
+	return runtimeCommands[s.runtime()], nil
+}
+
+func (s Spec) runtime() string {
+	if s.Runtime == "" {
+		return "claude"
+	}
+	return s.Runtime
+}
+
+// env returns the session environment: the caller's extra variables plus the
+// GT_AGENT, GT_ROLE and GT_RIG variables the registry reads back.
+func (s Spec) env() (map[string]string, error) {
+	env := make(map[string]string, len(s.Env)+3)
+	for k, v := range s.Env {
+		if err := checkEnvVar(k, v); err != nil {
+			return nil, invalidf("%v", err)
+		}
+		env[k] = v
+	}
+	env["GT_AGENT"] = s.runtime()
+	env["GT_ROLE"] = s.Role
+	if s.Rig != "" {
+		env["GT_RIG"] = s.Rig
+	}
+	return env, nil
+}
+
+// AuditDetail describes the spec for the audit log. Environment variable
+// values are omitted; they may hold secrets.
+func (s Spec) AuditDetail() map[string]any {
+	detail := map[string]any{"role": s.Role, "runtime": s.runtime()}
+	if s.Rig != "" {
+		detail["rig"] = s.Rig
+	}
+	if s.WorkDir != "" {
+		detail["workDir"] = s.WorkDir
+	}
+	if s.Command != "" {
+		detail["command"] = s.Command
+	}
+	if len(s.Env) > 0 {
+		keys := make([]string, 0, len(s.Env))
+		for k := range s.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		detail["env"] = keys
+	}
+	return detail
+}
+
+func hasControl(s string) bool {
+	return strings.ContainsFunc(s, unicode.IsControl)
+}
+
+func invalidf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSpec, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,92 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSpecSessionName(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		want string
+	}{
+		{Spec{Role: "mayor"}, "hq-mayor"},
+		{Spec{Role: "boot"}, "gt-boot"},
+		{Spec{Role: "witness", Rig: "myrig"}, "gt-myrig-witness"},
+		{Spec{Role: "crew", Rig: "myrig", Name: "bob"}, "gt-myrig-crew-bob"},
+		{Spec{Role: "polecat", Rig: "myrig", Name: "furiosa"}, "gt-myrig-furiosa"},
+	}
+	for _, tt := range tests {
+		got, err := tt.spec.SessionName()
+		if err != nil || got != tt.want {
+			t.Errorf("SessionName(%+v) = %q, %v; want %q", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestSpecSessionNameRejectsNonConformant(t *testing.T) {
+	for _, spec := range []Spec{
+		{Role: ""},
+		{Role: "mayor", Rig: "myrig"},                      // town-level role with a rig
+		{Role: "crew", Rig: "myrig"},                       // crew without a name
+		{Role: "witness"},                                  // rig role without a rig
+		{Role: "crew", Rig: "my-rig", Name: "bob"},         // dash in rig
+		{Role: "polecat", Rig: "myrig", Name: "crew"},      // reads back as crew
+		{Role: "polecat", Rig: "myrig", Name: "witness-2"}, // reads back as witness
+		{Role: "crew", Rig: "myrig", Name: "bob.1"},        // tmux rewrites dots
+		{Role: "adhoc"},                                    // reserved for non-gastown sessions
+	} {
+		if name, err := spec.SessionName(); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("SessionName(%+v) = %q, %v; want ErrInvalidSpec", spec, name, err)
+		}
+	}
+}
+
+func TestSpecWorkDirStaysInTown(t *testing.T) {
+	crew := Spec{Role: "crew", Rig: "myrig", Name: "bob"}
+	if dir, err := crew.workDir("/gt"); err != nil || dir != "/gt/myrig/crew/bob" {
+		t.Fatalf("workDir() = %q, %v", dir, err)
+	}
+	if dir, err := (Spec{Role: "mayor"}).workDir("/gt"); err != nil || dir != "/gt" {
+		t.Fatalf("town-level workDir() = %q, %v", dir, err)
+	}
+	for _, dir := range []string{"../elsewhere", "/tmp", "myrig/../../etc"} {
+		if _, err := (Spec{Role: "mayor", WorkDir: dir}).workDir("/gt"); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("workDir(%q) = %v, want ErrInvalidSpec", dir, err)
+		}
+	}
+}
+
+func TestSpecEnv(t *testing.T) {
+	spec := Spec{Role: "crew", Rig: "myrig", Name: "bob", Runtime: "codex", Env: map[string]string{"API_KEY": "secret"}}
+	env, err := spec.env()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["GT_AGENT"] != "codex" || env["GT_ROLE"] != "crew" || env["GT_RIG"] != "myrig" || env["API_KEY"] != "secret" {
+		t.Fatalf("env() = %v", env)
+	}
+	if detail := spec.AuditDetail(); detail["env"].([]string)[0] != "API_KEY" {
+		t.Fatalf("AuditDetail() = %v, want env names only", detail)
+	}
+
+	for _, extra := range []map[string]string{{"GT_ROLE": "mayor"}, {"BAD-NAME": "x"}, {"X": "a\nb"}} {
+		spec.Env = extra
+		if _, err := spec.env(); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("env(%v) = %v, want ErrInvalidSpec", extra, err)
+		}
+	}
+}
+
+func TestIsSecretEnv(t *testing.T) {
+	for _, key := range []string{"GITHUB_TOKEN", "AWS_SECRET_ACCESS_KEY", "db_password", "OPENAI_API_KEY", "SSH_KEY", "ANTHROPIC_AUTH"} {
+		if !IsSecretEnv(key) {
+			t.Errorf("IsSecretEnv(%q) = false", key)
+		}
+	}
+	for _, key := range []string{"FEATURE_FLAGS", "PATH", "KEYBOARD_LAYOUT", "GT_ROLE"} {
+		if IsSecretEnv(key) {
+			t.Errorf("IsSecretEnv(%q) = true", key)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+// RestartMode says when a supervised agent is respawned after its process
+// exits.
+type RestartMode string
+
+const (
+	RestartNever     RestartMode = "never"
+	RestartOnFailure RestartMode = "on-failure" // non-zero exit or signal
+	RestartAlways    RestartMode = "always"
+)
+
+// RestartPolicy configures how an agent is supervised.
+type RestartPolicy struct {
+	Mode        RestartMode
+	Backoff     time.Duration // delay before a restart, doubled per recent restart
+	MaxBackoff  time.Duration
+	MaxRestarts int           // restarts allowed within Window before a crash loop
+	Window      time.Duration // how far back restarts count
+}
+
+// DefaultRestartPolicy leaves agents unsupervised; the limits apply once a
+// mode is chosen.
+var DefaultRestartPolicy = RestartPolicy{
+	Mode:        RestartNever,
+	Backoff:     time.Second,
+	MaxBackoff:  30 * time.Second,
+	MaxRestarts: 5,
+	Window:      10 * time.Minute,
+}
+
+// backoff returns the delay before the restart following recent ones.
+func (p RestartPolicy) backoff(recent int) time.Duration {
+	delay := p.Backoff
+	for i := 0; i < recent && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxBackoff)
+}
+
+func (p RestartPolicy) validate() error {
+	switch p.Mode {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("unknown restart mode %q (want never, on-failure or always)", p.Mode)
+	}
+	if p.Backoff <= 0 || p.MaxBackoff < p.Backoff {
+		return fmt.Errorf("backoff must be positive and at most maxBackoff")
+	}
+	if p.MaxRestarts < 1 || p.Window <= 0 {
+		return fmt.Errorf("maxRestarts and window must be positive")
+	}
+	return nil
+}
+
+// restartPolicyJSON is the wire form of a RestartPolicy, with Go durations.
+type restartPolicyJSON struct {
+	Mode        RestartMode `json:"mode"`
+	Backoff     string      `json:"backoff"`
+	MaxBackoff  string      `json:"maxBackoff"`
+	MaxRestarts int         `json:"maxRestarts"`
+	Window      string      `json:"window"`
+}
+
+func (p RestartPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(restartPolicyJSON{
+		Mode:        p.Mode,
+		Backoff:     p.Backoff.String(),
+		MaxBackoff:  p.MaxBackoff.String(),
+		MaxRestarts: p.MaxRestarts,
+		Window:      p.Window.String(),
+	})
+}
+
+// UnmarshalJSON overrides the fields present in data, so unmarshalling into
+// a copy of a base policy fills in the rest.
+func (p *RestartPolicy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Mode        *RestartMode `json:"mode"`
+		Backoff     *string      `json:"backoff"`
+		MaxBackoff  *string      `json:"maxBackoff"`
+		MaxRestarts *int         `json:"maxRestarts"`
+		Window      *string      `json:"window"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Mode != nil {
+		p.Mode = *raw.Mode
+	}
+	if raw.MaxRestarts != nil {
+		p.MaxRestarts = *raw.MaxRestarts
+	}
+	for _, field := range []struct {
+		raw *string
+		dst *time.Duration
+	}{{raw.Backoff, &p.Backoff}, {raw.MaxBackoff, &p.MaxBackoff}, {raw.Window, &p.Window}} {
+		if field.raw == nil {
+			continue
+		}
+		d, err := time.ParseDuration(*field.raw)
+		if err != nil {
+			return err
+		}
+		*field.dst = d
+	}
+	return p.validate()
+}
+
+// RestartPolicies resolves an agent's policy by session name, then role,
+// then Default.
+type RestartPolicies struct {
+	Default RestartPolicy
+	Roles   map[string]RestartPolicy
+	Agents  map[string]RestartPolicy
+}
+
+// DefaultRestartPolicies supervises no agent.
+func DefaultRestartPolicies() RestartPolicies {
+	return RestartPolicies{Default: DefaultRestartPolicy}
+}
+
+// LoadRestartPoliciesFile reads a JSON config of the form
+// {"default":{"mode":"on-failure"},"roles":{"deacon":{"mode":"always","backoff":"3s"}},"agents":{"gt-myrig-crew-bob":{"mode":"never"}}}.
+// Role and agent entries start from the file's default, which starts from
+// DefaultRestartPolicy.
+func LoadRestartPoliciesFile(path string) (RestartPolicies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RestartPolicies{}, fmt.Errorf("read restart policies file: %w", err)
+	}
+	var raw struct {
+		Default json.RawMessage            `json:"default"`
+		Roles   map[string]json.RawMessage `json:"roles"`
+		Agents  map[string]json.RawMessage `json:"agents"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return RestartPolicies{}, fmt.Errorf("parse restart policies file %s: %w", path, err)
+	}
+
+	policies := RestartPolicies{
+		Default: DefaultRestartPolicy,
+		Roles:   make(map[string]RestartPolicy),
+		Agents:  make(map[string]RestartPolicy),
+	}
+	if raw.Default != nil {
+		if err := json.Unmarshal(raw.Default, &policies.Default); err != nil {
+			return RestartPolicies{}, fmt.Errorf("parse restart policies file %s: default: %w", path, err)
+		}
+	}
+	for _, section := range []struct {
+		raw map[string]json.RawMessage
+		dst map[string]RestartPolicy
+	}{{raw.Roles, policies.Roles}, {raw.Agents, policies.Agents}} {
+		for key, msg := range section.raw {
+			policy := policies.Default
+			if err := json.Unmarshal(msg, &policy); err != nil {
+				return RestartPolicies{}, fmt.Errorf("parse restart policies file %s: %s: %w", path, key, err)
+			}
+			section.dst[key] = policy
+		}
+	}
+	return policies, nil
+}
+
+// resolve returns the policy for an agent and where it came from.
+func (p RestartPolicies) resolve(name, role string) (RestartPolicy, string) {
+	if policy, ok := p.Agents[name]; ok {
+		return policy, "agent"
+	}
+	if policy, ok := p.Roles[role]; ok {
+		return policy, "role"
+	}
+	return p.Default, "default"
+}
+
+// Supervisor states.
+const (
+	StateRunning   = "running"
+	StateBackoff   = "backoff"    // waiting to respawn
+	StateCrashLoop = "crash-loop" // too many restarts within the window; given up
+	StateExited    = "exited"     // exited and not restarted under its policy
+)
+
+// ExitStatus is how a supervised agent's process ended.
+type ExitStatus struct {
+	ExitCode int       `json:"exitCode"`
+	Signal   int       `json:"signal,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// SupervisorStatus reports an agent's restart policy and history.
+type SupervisorStatus struct {
+	Policy         RestartPolicy `json:"policy"`
+	Source         string        `json:"source"` // "agent", "role" or "default"
+	State          string        `json:"state"`
+	Restarts       int           `json:"restarts"`       // automatic restarts since the adapter started
+	RecentRestarts int           `json:"recentRestarts"` // within the policy window
+	NextRestart    *time.Time    `json:"nextRestart,omitempty"`
+	LastExit       *ExitStatus   `json:"lastExit,omitempty"`
+}
+
+// supervision is the restart history of one agent.
+type supervision struct {
+	state    string
+	restarts []time.Time // recent restart times, oldest first
+	total    int
+	next     time.Time
+	timer    *time.Timer
+	lastExit *ExitStatus
+}
+
+// recent drops restarts older than window and returns how many remain.
+func (s *supervision) recent(now time.Time, window time.Duration) int {
+	s.restarts = slices.DeleteFunc(s.restarts, func(t time.Time) bool { return now.Sub(t) >= window })
+	return len(s.restarts)
+}
+
+// HandleExit applies an agent's restart policy when its pane dies (see
+// agents.ExitHandler). It returns true when a respawn is scheduled, leaving
+// the dead pane in place until then.
+func (m *Manager) HandleExit(agent agents.Agent, pane tmux.DeadPane) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy, _ := m.policies.resolve(agent.Name, agent.Role)
+	s := m.supervisionFor(agent.Name)
+	exit := ExitStatus{ExitCode: pane.Status, Signal: pane.Signal, Time: pane.Time}
+	if pane.Signal != 0 {
+		exit.ExitCode = 128 + pane.Signal
+	}
+	s.lastExit = &exit
+
+	failed := exit.ExitCode != 0
+	if policy.Mode == RestartNever || (policy.Mode == RestartOnFailure && !failed) {
+		s.state = StateExited
+		return false
+	}
+	now := time.Now()
+	recent := s.recent(now, policy.Window)
+	if recent >= policy.MaxRestarts {
+		s.state = StateCrashLoop
+		log.Printf("supervise %s: crash loop (%d restarts within %s); not restarting", agent.Name, recent, policy.Window)
+		return false
+	}
+
+	delay := policy.backoff(recent)
+	s.state, s.next = StateBackoff, now.Add(delay)
+	s.restarts = append(s.restarts, now)
+	s.total++
+	log.Printf("supervise %s: exited with %d; restarting in %s (%d/%d within %s)", agent.Name, exit.ExitCode, delay, recent+1, policy.MaxRestarts, policy.Window)
+	s.timer = time.AfterFunc(delay, func() { m.respawn(agent.Name, pane) })
+	return true
+}
+
+// respawn restarts a dead agent pane, unless it was killed or replaced during
+// the backoff.
+func (m *Manager) respawn(name string, dead tmux.DeadPane) {
+	m.mu.Lock()
+	s := m.supervisionFor(name)
+	pending := s.state == StateBackoff
+	if pending {
+		s.state, s.timer = StateRunning, nil
+	}
+	m.mu.Unlock()
+	if !pending {
+		return
+	}
+	if pids, err := m.ctrl.PanePIDs(name); err != nil || !slices.Contains(pids, dead.PID) {
+		log.Printf("supervise %s: pane gone or replaced during backoff; not restarting", name)
+		return
+	}
+	m.registry.ExpectRestart(name)
+	if err := m.ctrl.RespawnPane(dead.PaneID); err != nil {
+		log.Printf("supervise %s: respawn-pane: %v", name, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.spawnTimeout)
+	defer cancel()
+	if _, pid, err := m.waitForAgent(ctx, name, dead.PID); err != nil {
+		log.Printf("supervise %s: %v", name, err)
+	} else {
+		log.Printf("supervise %s: restarted (pid %d)", name, pid)
+	}
+}
+
+// supervisionFor returns name's supervision record. Called with mu held.
+func (m *Manager) supervisionFor(name string) *supervision {
+	s, ok := m.supervised[name]
+	if !ok {
+		s = &supervision{state: StateRunning}
+		m.supervised[name] = s
+	}
+	return s
+}
+
+// forget clears an agent's restart history and any pending restart, e.g.
+// when it is stopped or spawned afresh.
+func (m *Manager) forget(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.supervised[name]; ok && s.timer != nil {
+		s.timer.Stop()
+	}
+	delete(m.supervised, name)
+}
+
+// Supervision reports an agent's restart policy and history.
+func (m *Manager) Supervision(name string) SupervisorStatus {
+	role, _ := agents.ParseSessionName(name)
+	if agent, ok := m.registry.GetAgent(name); ok {
+		role = agent.Role
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy, source := m.policies.resolve(name, role)
+	status := SupervisorStatus{Policy: policy, Source: source, State: StateRunning}
+	if s, ok := m.supervised[name]; ok {
+		status.State = s.state
+		status.Restarts = s.total
+		status.RecentRestarts = s.recent(time.Now(), policy.Window)
+		status.LastExit = s.lastExit
+		if s.state == StateBackoff {
+			next := s.next
+			status.NextRestart = &next
+		}
+	}
+	return status
+}
+
+// SetRestartPolicy overrides an agent's restart policy until the adapter
+// restarts.
+func (m *Manager) SetRestartPolicy(name string, policy RestartPolicy) error {
+	if err := policy.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.policies.Agents == nil {
+		m.policies.Agents = make(map[string]RestartPolicy)
+	}
+	m.policies.Agents[name] = policy
+	return nil
+}
+
+// ClearRestartPolicy removes an agent's override, falling back to its role's
+// policy or the default.
+func (m *Manager) ClearRestartPolicy(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.policies.Agents, name)
+}
+
+// RestartPolicy returns the policy that currently applies to an agent, as a
+// base for partial updates.
+func (m *Manager) RestartPolicy(name string) RestartPolicy {
+	return m.Supervision(name).Policy
+}
@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	p := RestartPolicy{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for recent, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if got := p.backoff(recent); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", recent, got, want)
+		}
+	}
+}
+
+func TestLoadRestartPoliciesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	config := `{
+		"default": {"mode": "on-failure", "maxRestarts": 3},
+		"roles": {"deacon": {"mode": "always", "backoff": "3s"}},
+		"agents": {"gt-myrig-crew-bob": {"mode": "never"}}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := LoadRestartPoliciesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, source := policies.resolve("hq-deacon", "deacon"); source != "role" || p.Mode != RestartAlways || p.Backoff != 3*time.Second || p.MaxRestarts != 3 {
+		t.Errorf("deacon policy = %+v from %s, want always/3s inheriting maxRestarts 3", p, source)
+	}
+	if p, source := policies.resolve("gt-myrig-crew-bob", "crew"); source != "agent" || p.Mode != RestartNever {
+		t.Errorf("bob policy = %+v from %s, want never", p, source)
+	}
+	if p, source := policies.resolve("gt-myrig-witness", "witness"); source != "default" || p.Mode != RestartOnFailure || p.Window != DefaultRestartPolicy.Window {
+		t.Errorf("witness policy = %+v from %s, want the default", p, source)
+	}
+
+	for _, bad := range []string{`{"default":{"mode":"sometimes"}}`, `{"roles":{"deacon":{"backoff":"1m","maxBackoff":"1s"}}}`, `{"agents":{"x":{"window":"soon"}}}`} {
+		os.WriteFile(path, []byte(bad), 0o600)
+		if _, err := LoadRestartPoliciesFile(path); err == nil {
+			t.Errorf("LoadRestartPoliciesFile(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestRestartPolicyJSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(DefaultRestartPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p RestartPolicy
+	if err := json.Unmarshal(data, &p); err != nil || p != DefaultRestartPolicy {
+		t.Fatalf("round trip of %s = %+v, %v", data, p, err)
+	}
+}
+
+func TestHandleExitAppliesPolicy(t *testing.T) {
+	policy := RestartPolicy{Mode: RestartOnFailure, Backoff: time.Hour, MaxBackoff: time.Hour, MaxRestarts: 2, Window: time.Hour}
+	m := &Manager{
+		policies:   RestartPolicies{Default: DefaultRestartPolicy, Roles: map[string]RestartPolicy{"crew": policy}},
+		supervised: make(map[string]*supervision),
+	}
+	bob := agents.Agent{Name: "gt-myrig-crew-bob", Role: "crew"}
+	t.Cleanup(func() { m.forget(bob.Name) })
+
+	if m.HandleExit(bob, tmux.DeadPane{PID: 100, Status: 0}) {
+		t.Fatal("on-failure restarted a clean exit")
+	}
+	for i := range 2 {
+		if !m.HandleExit(bob, tmux.DeadPane{PID: 101 + i, Status: -1, Signal: 9}) {
+			t.Fatalf("crash %d was not restarted", i+1)
+		}
+		m.supervised[bob.Name].timer.Stop()
+	}
+	if m.HandleExit(bob, tmux.DeadPane{PID: 103, Status: 1}) {
+		t.Fatal("restarted beyond maxRestarts")
+	}
+	s := m.supervised[bob.Name]
+	if s.state != StateCrashLoop || s.total != 2 || s.lastExit.ExitCode != 1 {
+		t.Fatalf("supervision = %+v, want crash-loop after 2 restarts", s)
+	}
+
+	mayor := agents.Agent{Name: "hq-mayor", Role: "mayor"}
+	if m.HandleExit(mayor, tmux.DeadPane{PID: 200, Status: -1, Signal: 9}) || m.supervised[mayor.Name].state != StateExited {
+		t.Fatal("the default never policy restarted an agent")
+	}
+}
@@ -28,11 +28,11 @@ func GetLock(agentName string) *sync.Mutex {
 	return locks[agentName]
 }
 
-// Session sends a prompt to an agent's tmux session.
+// Session sends a prompt to the pane running an agent.
 // Full sequence: literal text → 500ms paste pause → Escape → Enter (3x retry) → SIGWINCH wake.
 // The caller must hold GetLock(agent.Name) before calling.
 func Session(ctrl *tmux.ControlMode, agent agents.Agent, prompt string) error {
-	session := agent.Name
+	session := agent.Target()
 
 	// 1. Send text in literal mode
 	if err := ctrl.SendKeysLiteral(session, prompt); err != nil {
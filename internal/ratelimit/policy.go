@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+// Kind is a class of rate-limited activity.
+type Kind string
+
+const (
+	KindAuth   Kind = "auth"   // failed authentication attempts, per remote host or socket peer
+	KindREST   Kind = "rest"   // REST and SSE requests
+	KindWS     Kind = "ws"     // WebSocket messages, including keyboard frames
+	KindUpload Kind = "upload" // file uploads
+)
+
+var kinds = []Kind{KindAuth, KindREST, KindWS, KindUpload}
+
+// Limits are the per-caller rates for one scope.
+type Limits struct {
+	REST   Rate `json:"rest"`
+	WS     Rate `json:"ws"`
+	Upload Rate `json:"upload"`
+}
+
+func (l Limits) rate(kind Kind) Rate {
+	switch kind {
+	case KindREST:
+		return l.REST
+	case KindWS:
+		return l.WS
+	case KindUpload:
+		return l.Upload
+	}
+	return Rate{}
+}
+
+// Config configures all limiters.
+type Config struct {
+	AuthFailures Rate                  `json:"authFailures"`
+	Scopes       map[auth.Scope]Limits `json:"scopes"`
+}
+
+// DefaultConfig allows interactive use comfortably (fast typing, terminal
+// resizes while dragging) while stopping floods and token guessing.
+var DefaultConfig = Config{
+	AuthFailures: Rate{PerSecond: 1.0 / 6, Burst: 10}, // 10 per minute
+	Scopes: map[auth.Scope]Limits{
+		auth.ScopeRead: {
+			REST:   Rate{PerSecond: 20, Burst: 50},
+			WS:     Rate{PerSecond: 50, Burst: 100},
+			Upload: Rate{PerSecond: 1, Burst: 5},
+		},
+		auth.ScopeOperate: {
+			REST:   Rate{PerSecond: 20, Burst: 50},
+			WS:     Rate{PerSecond: 200, Burst: 400},
+			Upload: Rate{PerSecond: 1, Burst: 5},
+		},
+		auth.ScopeAdmin: {
+			REST:   Rate{PerSecond: 50, Burst: 100},
+			WS:     Rate{PerSecond: 200, Burst: 400},
+			Upload: Rate{PerSecond: 2, Burst: 10},
+		},
+	},
+}
+
+// LoadConfigFile reads a JSON config of the form
+// {"authFailures":{"perSecond":0.1,"burst":5},"scopes":{"read":{"ws":{"perSecond":10,"burst":20}}}}.
+// Anything not mentioned keeps its DefaultConfig value.
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read rate limits file: %w", err)
+	}
+	var raw struct {
+		AuthFailures *Rate                          `json:"authFailures"`
+		Scopes       map[auth.Scope]json.RawMessage `json:"scopes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Config{}, fmt.Errorf("parse rate limits file %s: %w", path, err)
+	}
+
+	cfg := Config{AuthFailures: DefaultConfig.AuthFailures, Scopes: make(map[auth.Scope]Limits)}
+	for scope, limits := range DefaultConfig.Scopes {
+		cfg.Scopes[scope] = limits
+	}
+	if raw.AuthFailures != nil {
+		cfg.AuthFailures = *raw.AuthFailures
+	}
+	for scope, msg := range raw.Scopes {
+		limits := cfg.Scopes[scope]
+		if err := json.Unmarshal(msg, &limits); err != nil {
+			return Config{}, fmt.Errorf("parse rate limits file %s: scope %s: %w", path, scope, err)
+		}
+		cfg.Scopes[scope] = limits
+	}
+	return cfg, nil
+}
+
+// Limiters applies a Config. A nil *Limiters allows everything.
+type Limiters struct {
+	authFailures *Limiter
+	scopes       map[auth.Scope]map[Kind]*Limiter
+	rejected     map[Kind]*atomic.Int64
+}
+
+// New creates limiters for cfg.
+func New(cfg Config) *Limiters {
+	l := &Limiters{
+		authFailures: NewLimiter(cfg.AuthFailures),
+		scopes:       make(map[auth.Scope]map[Kind]*Limiter),
+		rejected:     make(map[Kind]*atomic.Int64),
+	}
+	for scope, limits := range cfg.Scopes {
+		l.scopes[scope] = make(map[Kind]*Limiter)
+		for _, kind := range []Kind{KindREST, KindWS, KindUpload} {
+			l.scopes[scope][kind] = NewLimiter(limits.rate(kind))
+		}
+	}
+	for _, kind := range kinds {
+		l.rejected[kind] = new(atomic.Int64)
+	}
+	return l
+}
+
+// AuthBlocked reports whether the request's source has exhausted its failed
+// authentication allowance; such requests should be refused without checking
+// credentials.
+func (l *Limiters) AuthBlocked(r *http.Request) (bool, time.Duration) {
+	if l == nil {
+		return false, 0
+	}
+	blocked, wait := l.authFailures.Blocked(authSource(r))
+	if blocked {
+		l.rejected[KindAuth].Add(1)
+	}
+	return blocked, wait
+}
+
+// AuthFailed records a failed authentication attempt by the request's source.
+func (l *Limiters) AuthFailed(r *http.Request) {
+	if l == nil {
+		return
+	}
+	l.authFailures.Allow(authSource(r))
+}
+
+// authSource keys failed authentication attempts. Unix socket callers all
+// share an empty remote address, so they are told apart by peer uid; one
+// local user's failures must not lock out another.
+func authSource(r *http.Request) string {
+	if cred, ok := auth.PeerFrom(r.Context()); ok {
+		return "uid:" + strconv.Itoa(cred.UID)
+	}
+	return "host:" + RemoteHost(r.RemoteAddr)
+}
+
+// Allow takes a token for kind from the caller's bucket, using the limits of
+// the identity's scope. Callers are keyed by identity name, or by remote host
+// for anonymous callers (auth disabled).
+func (l *Limiters) Allow(kind Kind, id auth.Identity, remote string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	limiter, ok := l.scopes[id.Scope][kind]
+	if !ok {
+		return true, 0
+	}
+	key := "id:" + id.Name
+	if id.Name == auth.AnonymousName {
+		key = "host:" + RemoteHost(remote)
+	}
+	allowed, wait := limiter.Allow(key)
+	if !allowed {
+		l.rejected[kind].Add(1)
+	}
+	return allowed, wait
+}
+
+// Rejected returns the number of rejected requests per kind.
+func (l *Limiters) Rejected() map[Kind]int64 {
+	counts := make(map[Kind]int64)
+	if l == nil {
+		return counts
+	}
+	for kind, n := range l.rejected {
+		counts[kind] = n.Load()
+	}
+	return counts
+}
+
+// RetryAfter formats wait as a Retry-After header value (whole seconds, at
+// least 1).
+func RetryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+}
+
+// RemoteHost strips the port from an http.Request.RemoteAddr.
+func RemoteHost(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
@@ -0,0 +1,109 @@
+// Package ratelimit provides token-bucket rate limiting for API requests,
+// WebSocket messages, uploads and failed authentication attempts.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often idle buckets are dropped.
+const sweepInterval = time.Minute
+
+// Rate is a sustained rate with a burst allowance. A non-positive PerSecond
+// means unlimited.
+type Rate struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+}
+
+func (r Rate) unlimited() bool {
+	return r.PerSecond <= 0
+}
+
+func (r Rate) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return math.Max(1, math.Ceil(r.PerSecond))
+}
+
+// Limiter holds one token bucket per key.
+type Limiter struct {
+	rate Rate
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter; each key starts with a full bucket.
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. If none is available it reports
+// false and how long until one will be.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.take(key, true)
+}
+
+// Blocked reports whether key's bucket is empty, without taking a token.
+func (l *Limiter) Blocked(key string) (bool, time.Duration) {
+	ok, wait := l.take(key, false)
+	return !ok, wait
+}
+
+func (l *Limiter) take(key string, consume bool) (bool, time.Duration) {
+	if l.rate.unlimited() {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.rate.burst(), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.rate.burst(), b.tokens+now.Sub(b.last).Seconds()*l.rate.PerSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate.PerSecond * float64(time.Second))
+		return false, wait
+	}
+	if consume {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sweep drops buckets that have refilled completely; they are
+// indistinguishable from new ones. The caller holds l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	full := l.rate.burst()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate.PerSecond >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
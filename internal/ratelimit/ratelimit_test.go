@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := NewLimiter(Rate{PerSecond: 2, Burst: 3})
+	l.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("Allow() after burst = %v, %v; want false, 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("keys must have independent buckets")
+	}
+
+	clock.advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("bucket did not refill")
+	}
+}
+
+func TestLimiterBlockedDoesNotConsume(t *testing.T) {
+	l := NewLimiter(Rate{PerSecond: 1, Burst: 1})
+	for i := 0; i < 3; i++ {
+		if blocked, _ := l.Blocked("a"); blocked {
+			t.Fatal("Blocked() must not take tokens")
+		}
+	}
+	l.Allow("a")
+	if blocked, _ := l.Blocked("a"); !blocked {
+		t.Fatal("empty bucket not reported as blocked")
+	}
+}
+
+func TestLimiterSweepsIdleBuckets(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := NewLimiter(Rate{PerSecond: 1, Burst: 5})
+	l.now = clock.now
+	l.Allow("idle")
+
+	clock.advance(2 * sweepInterval)
+	l.Allow("other")
+	if _, ok := l.buckets["idle"]; ok {
+		t.Fatal("refilled bucket not swept")
+	}
+}
+
+func TestLimitersPerScopeAndAnonymous(t *testing.T) {
+	l := New(Config{Scopes: map[auth.Scope]Limits{
+		auth.ScopeRead:  {WS: Rate{PerSecond: 1, Burst: 1}},
+		auth.ScopeAdmin: {WS: Rate{PerSecond: 1, Burst: 2}},
+	}})
+	reader := auth.Identity{Name: "viewer", Scope: auth.ScopeRead}
+	admin := auth.Identity{Name: "root", Scope: auth.ScopeAdmin}
+
+	if ok, _ := l.Allow(KindWS, reader, "10.0.0.1:1"); !ok {
+		t.Fatal("first read message rejected")
+	}
+	// Same token from another address shares the bucket.
+	if ok, _ := l.Allow(KindWS, reader, "10.0.0.2:1"); ok {
+		t.Fatal("read scope burst not enforced per token")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow(KindWS, admin, "10.0.0.1:1"); !ok {
+			t.Fatal("admin scope limits not applied")
+		}
+	}
+	if ok, _ := l.Allow(KindREST, reader, "10.0.0.1:1"); !ok {
+		t.Fatal("unconfigured rate must be unlimited")
+	}
+
+	anon := auth.Identity{Name: auth.AnonymousName, Scope: auth.ScopeAdmin}
+	for _, remote := range []string{"10.0.0.1:1", "10.0.0.2:1"} {
+		if ok, _ := l.Allow(KindWS, anon, remote); !ok {
+			t.Fatalf("anonymous caller %s shares another host's bucket", remote)
+		}
+	}
+	if got := l.Rejected()[KindWS]; got != 1 {
+		t.Fatalf("Rejected()[ws] = %d, want 1", got)
+	}
+}
+
+func TestLimitersAuthFailures(t *testing.T) {
+	l := New(Config{AuthFailures: Rate{PerSecond: 0.1, Burst: 2}})
+	from := func(remote string) *http.Request {
+		r := httptest.NewRequest("GET", "/api/agents", nil)
+		r.RemoteAddr = remote
+		return r
+	}
+	for i := 0; i < 2; i++ {
+		if blocked, _ := l.AuthBlocked(from("10.0.0.1:5000")); blocked {
+			t.Fatalf("blocked after %d failures", i)
+		}
+		l.AuthFailed(from("10.0.0.1:5000"))
+	}
+	if blocked, wait := l.AuthBlocked(from("10.0.0.1:6000")); !blocked || wait <= 0 {
+		t.Fatalf("AuthBlocked() = %v, %v; want blocked by host", blocked, wait)
+	}
+	if blocked, _ := l.AuthBlocked(from("10.0.0.2:5000")); blocked {
+		t.Fatal("other hosts must not be blocked")
+	}
+}
+
+func TestLimitersAuthFailuresByPeer(t *testing.T) {
+	l := New(Config{AuthFailures: Rate{PerSecond: 0.1, Burst: 2}})
+	from := func(uid int) *http.Request {
+		r := httptest.NewRequest("GET", "/api/agents", nil)
+		r.RemoteAddr = "" // as for every Unix socket connection
+		return r.WithContext(auth.WithPeer(r.Context(), auth.PeerCred{UID: uid}))
+	}
+	for range 3 {
+		l.AuthFailed(from(1001))
+	}
+	if blocked, _ := l.AuthBlocked(from(1001)); !blocked {
+		t.Fatal("failing peer was not blocked")
+	}
+	if blocked, _ := l.AuthBlocked(from(1000)); blocked {
+		t.Fatal("another local user was blocked by a different uid's failures")
+	}
+}
+
+func TestNilLimitersAllowEverything(t *testing.T) {
+	var l *Limiters
+	if ok, _ := l.Allow(KindREST, auth.Identity{}, ""); !ok {
+		t.Fatal("nil limiters rejected a request")
+	}
+	if blocked, _ := l.AuthBlocked(httptest.NewRequest("GET", "/", nil)); blocked {
+		t.Fatal("nil limiters blocked auth")
+	}
+}
+
+func TestLoadConfigFileMergesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	data := `{"scopes":{"read":{"ws":{"perSecond":5,"burst":10}}}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	read := cfg.Scopes[auth.ScopeRead]
+	if read.WS != (Rate{PerSecond: 5, Burst: 10}) {
+		t.Fatalf("read ws = %+v", read.WS)
+	}
+	if read.REST != DefaultConfig.Scopes[auth.ScopeRead].REST || cfg.AuthFailures != DefaultConfig.AuthFailures {
+		t.Fatal("unspecified limits must keep their defaults")
+	}
+	if DefaultConfig.Scopes[auth.ScopeRead].WS.PerSecond == 5 {
+		t.Fatal("LoadConfigFile modified DefaultConfig")
+	}
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//go:generate go run gen_font.go -o font.bin.gz
+
+// fontAtlas is a pre-rasterized monospace font (see gen_font.go).
+//
+//go:embed font.bin.gz
+var fontAtlas []byte
+
+// FontLicense is the font atlas's license notice, which ships with the
+// binary alongside the atlas.
+//
+//go:embed FONT-LICENSE
+var FontLicense string
+
+// bitmapFont holds one anti-aliased alpha mask per code point.
+type bitmapFont struct {
+	cellWidth  int
+	cellHeight int
+	glyphs     map[rune][]byte
+}
+
+var (
+	defaultFont     *bitmapFont
+	defaultFontErr  error
+	defaultFontOnce sync.Once
+)
+
+// loadFont decodes the embedded atlas once.
+func loadFont() (*bitmapFont, error) {
+	defaultFontOnce.Do(func() {
+		defaultFont, defaultFontErr = decodeFont(fontAtlas)
+	})
+	return defaultFont, defaultFontErr
+}
+
+func decodeFont(compressed []byte) (*bitmapFont, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("font atlas: %w", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("font atlas: %w", err)
+	}
+	if len(data) < 10 || string(data[:4]) != "TAF1" {
+		return nil, fmt.Errorf("font atlas: bad header")
+	}
+
+	f := &bitmapFont{
+		cellWidth:  int(data[4]),
+		cellHeight: int(data[5]),
+		glyphs:     make(map[rune][]byte),
+	}
+	count := int(binary.LittleEndian.Uint32(data[6:]))
+	size := f.cellWidth * f.cellHeight
+	p := 10
+	for i := 0; i < count; i++ {
+		if p+4+size > len(data) {
+			return nil, fmt.Errorf("font atlas: truncated at glyph %d", i)
+		}
+		r := rune(binary.LittleEndian.Uint32(data[p:]))
+		f.glyphs[r] = data[p+4 : p+4+size]
+		p += 4 + size
+	}
+	return f, nil
+}
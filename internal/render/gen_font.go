@@ -0,0 +1,466 @@
+//go:build ignore
+
+// gen_font rasterizes a monospace TrueType font into the bitmap atlas
+// embedded by the render package (font.bin.gz).
+//
+// Usage:
+//
+//	go run gen_font.go -ttf /usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf -o font.bin.gz
+//
+// The atlas is derived from DejaVu Sans Mono; its Bitstream Vera license is
+// in FONT-LICENSE and must ship with the atlas.
+// font.bin.gz is gzip-compressed; uncompressed layout (integers little-endian):
+//
+//	magic "TAF1"
+//	uint8 cellWidth, uint8 cellHeight
+//	uint32 glyphCount
+//	glyphCount × { uint32 codepoint, cellWidth*cellHeight alpha bytes }
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	cellWidth   = 9
+	cellHeight  = 18
+	pixelsEm    = 15.0 // font size; DejaVu Sans Mono advance (0.6em) ≈ 9px
+	supersample = 4
+)
+
+// ranges lists the code points included in the atlas.
+var ranges = [][2]rune{
+	{0x20, 0x7e},     // ASCII
+	{0xa0, 0xff},     // Latin-1 supplement
+	{0x2010, 0x2027}, // dashes, quotes, bullet, ellipsis
+	{0x2190, 0x21ff}, // arrows
+	{0x2300, 0x23ff}, // misc technical (⎿, ⏺)
+	{0x2500, 0x259f}, // box drawing and block elements
+	{0x25a0, 0x25ff}, // geometric shapes
+	{0x2600, 0x26ff}, // misc symbols
+	{0x2700, 0x27bf}, // dingbats (✓, ✗)
+}
+
+func main() {
+	ttfPath := flag.String("ttf", "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf", "TrueType font to rasterize")
+	outPath := flag.String("o", "font.bin.gz", "output atlas path")
+	flag.Parse()
+
+	data, err := os.ReadFile(*ttfPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := parseFont(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scale := pixelsEm / float64(f.unitsPerEm)
+	// Center the ascent/descent box vertically in the cell.
+	fontHeight := float64(f.ascent-f.descent) * scale
+	baseline := (cellHeight-fontHeight)/2 + float64(f.ascent)*scale
+
+	var out bytes.Buffer
+	out.WriteString("TAF1")
+	out.WriteByte(cellWidth)
+	out.WriteByte(cellHeight)
+
+	var glyphs []rune
+	for _, r := range ranges {
+		for c := r[0]; c <= r[1]; c++ {
+			if gid := f.glyphIndex(c); gid != 0 {
+				glyphs = append(glyphs, c)
+			}
+		}
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	binary.Write(&out, binary.LittleEndian, uint32(len(glyphs)))
+
+	for _, c := range glyphs {
+		contours, err := f.glyphContours(f.glyphIndex(c), 0)
+		if err != nil {
+			log.Fatalf("glyph U+%04X: %v", c, err)
+		}
+		binary.Write(&out, binary.LittleEndian, uint32(c))
+		out.Write(rasterize(contours, scale, baseline))
+	}
+
+	var gz bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := zw.Write(out.Bytes()); err != nil {
+		log.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outPath, gz.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %d glyphs (%dx%d) to %s\n", len(glyphs), cellWidth, cellHeight, *outPath)
+}
+
+type point struct {
+	x, y    float64
+	onCurve bool
+}
+
+type font struct {
+	data       []byte
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	longLoca   bool
+	cmap       []byte // format 4 subtable
+}
+
+func parseFont(data []byte) (*font, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("short font file")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	f := &font{data: data, tables: map[string][]byte{}}
+	for i := 0; i < numTables; i++ {
+		rec := data[12+16*i:]
+		tag := string(rec[:4])
+		off := binary.BigEndian.Uint32(rec[8:])
+		length := binary.BigEndian.Uint32(rec[12:])
+		f.tables[tag] = data[off : off+length]
+	}
+	for _, tag := range []string{"head", "hhea", "loca", "glyf", "cmap"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := f.tables["hhea"]
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+
+	cmap := f.tables["cmap"]
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		rec := cmap[4+8*i:]
+		platform := binary.BigEndian.Uint16(rec)
+		encoding := binary.BigEndian.Uint16(rec[2:])
+		off := binary.BigEndian.Uint32(rec[4:])
+		if (platform == 3 && encoding == 1) || platform == 0 {
+			sub := cmap[off:]
+			if binary.BigEndian.Uint16(sub) == 4 {
+				f.cmap = sub
+				break
+			}
+		}
+	}
+	if f.cmap == nil {
+		return nil, fmt.Errorf("no format 4 cmap subtable")
+	}
+	return f, nil
+}
+
+// glyphIndex maps a BMP code point to a glyph ID via the format 4 cmap.
+func (f *font) glyphIndex(c rune) int {
+	sub := f.cmap
+	segX2 := int(binary.BigEndian.Uint16(sub[6:]))
+	ends := sub[14:]
+	starts := sub[16+segX2:]
+	deltas := sub[16+2*segX2:]
+	rangeOffsets := sub[16+3*segX2:]
+	for i := 0; i < segX2; i += 2 {
+		end := rune(binary.BigEndian.Uint16(ends[i:]))
+		if c > end {
+			continue
+		}
+		start := rune(binary.BigEndian.Uint16(starts[i:]))
+		if c < start {
+			return 0
+		}
+		delta := int(int16(binary.BigEndian.Uint16(deltas[i:])))
+		ro := int(binary.BigEndian.Uint16(rangeOffsets[i:]))
+		if ro == 0 {
+			return (int(c) + delta) & 0xffff
+		}
+		idx := i + ro + 2*int(c-start)
+		gid := int(binary.BigEndian.Uint16(rangeOffsets[idx:]))
+		if gid == 0 {
+			return 0
+		}
+		return (gid + delta) & 0xffff
+	}
+	return 0
+}
+
+func (f *font) glyphData(gid int) []byte {
+	loca := f.tables["loca"]
+	var start, end uint32
+	if f.longLoca {
+		start = binary.BigEndian.Uint32(loca[4*gid:])
+		end = binary.BigEndian.Uint32(loca[4*gid+4:])
+	} else {
+		start = 2 * uint32(binary.BigEndian.Uint16(loca[2*gid:]))
+		end = 2 * uint32(binary.BigEndian.Uint16(loca[2*gid+2:]))
+	}
+	return f.tables["glyf"][start:end]
+}
+
+// glyphContours returns the outline contours of a glyph in font units.
+func (f *font) glyphContours(gid, depth int) ([][]point, error) {
+	if depth > 8 {
+		return nil, fmt.Errorf("composite glyph nesting too deep")
+	}
+	g := f.glyphData(gid)
+	if len(g) == 0 {
+		return nil, nil // e.g. space
+	}
+	numContours := int(int16(binary.BigEndian.Uint16(g)))
+	if numContours < 0 {
+		return f.compositeContours(g[10:], depth)
+	}
+
+	p := 10
+	endPts := make([]int, numContours)
+	for i := range endPts {
+		endPts[i] = int(binary.BigEndian.Uint16(g[p:]))
+		p += 2
+	}
+	numPoints := 0
+	if numContours > 0 {
+		numPoints = endPts[numContours-1] + 1
+	}
+	instrLen := int(binary.BigEndian.Uint16(g[p:]))
+	p += 2 + instrLen
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		fl := g[p]
+		p++
+		flags = append(flags, fl)
+		if fl&8 != 0 {
+			repeat := int(g[p])
+			p++
+			for j := 0; j < repeat; j++ {
+				flags = append(flags, fl)
+			}
+		}
+	}
+
+	xs := make([]int, numPoints)
+	x := 0
+	for i, fl := range flags {
+		switch {
+		case fl&2 != 0:
+			d := int(g[p])
+			p++
+			if fl&16 == 0 {
+				d = -d
+			}
+			x += d
+		case fl&16 == 0:
+			x += int(int16(binary.BigEndian.Uint16(g[p:])))
+			p += 2
+		}
+		xs[i] = x
+	}
+	ys := make([]int, numPoints)
+	y := 0
+	for i, fl := range flags {
+		switch {
+		case fl&4 != 0:
+			d := int(g[p])
+			p++
+			if fl&32 == 0 {
+				d = -d
+			}
+			y += d
+		case fl&32 == 0:
+			y += int(int16(binary.BigEndian.Uint16(g[p:])))
+			p += 2
+		}
+		ys[i] = y
+	}
+
+	contours := make([][]point, 0, numContours)
+	start := 0
+	for _, end := range endPts {
+		c := make([]point, 0, end-start+1)
+		for i := start; i <= end; i++ {
+			c = append(c, point{x: float64(xs[i]), y: float64(ys[i]), onCurve: flags[i]&1 != 0})
+		}
+		contours = append(contours, c)
+		start = end + 1
+	}
+	return contours, nil
+}
+
+func (f *font) compositeContours(g []byte, depth int) ([][]point, error) {
+	var all [][]point
+	for {
+		fl := binary.BigEndian.Uint16(g)
+		gid := int(binary.BigEndian.Uint16(g[2:]))
+		p := 4
+		var dx, dy float64
+		if fl&1 != 0 { // ARG_1_AND_2_ARE_WORDS
+			dx = float64(int16(binary.BigEndian.Uint16(g[p:])))
+			dy = float64(int16(binary.BigEndian.Uint16(g[p+2:])))
+			p += 4
+		} else {
+			dx = float64(int8(g[p]))
+			dy = float64(int8(g[p+1]))
+			p += 2
+		}
+		if fl&2 == 0 { // ARGS_ARE_XY_VALUES unset: point matching, unsupported
+			dx, dy = 0, 0
+		}
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		f2dot14 := func(off int) float64 { return float64(int16(binary.BigEndian.Uint16(g[off:]))) / 16384 }
+		switch {
+		case fl&8 != 0: // WE_HAVE_A_SCALE
+			a = f2dot14(p)
+			d = a
+			p += 2
+		case fl&0x40 != 0: // WE_HAVE_AN_X_AND_Y_SCALE
+			a, d = f2dot14(p), f2dot14(p+2)
+			p += 4
+		case fl&0x80 != 0: // WE_HAVE_A_TWO_BY_TWO
+			a, b, c, d = f2dot14(p), f2dot14(p+2), f2dot14(p+4), f2dot14(p+6)
+			p += 8
+		}
+
+		sub, err := f.glyphContours(gid, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, contour := range sub {
+			for i, pt := range contour {
+				contour[i].x = a*pt.x + c*pt.y + dx
+				contour[i].y = b*pt.x + d*pt.y + dy
+			}
+			all = append(all, contour)
+		}
+
+		if fl&0x20 == 0 { // MORE_COMPONENTS
+			return all, nil
+		}
+		g = g[p:]
+	}
+}
+
+type segment struct{ x0, y0, x1, y1 float64 }
+
+// flatten converts quadratic TrueType contours into line segments in pixel space.
+func flatten(contours [][]point, scale, baseline float64) []segment {
+	var segs []segment
+	for _, c := range contours {
+		if len(c) == 0 {
+			continue
+		}
+		// Expand implied on-curve points between consecutive off-curve points.
+		var pts []point
+		for i := range c {
+			cur := c[i]
+			next := c[(i+1)%len(c)]
+			pts = append(pts, cur)
+			if !cur.onCurve && !next.onCurve {
+				pts = append(pts, point{x: (cur.x + next.x) / 2, y: (cur.y + next.y) / 2, onCurve: true})
+			}
+		}
+		// Rotate so we start on an on-curve point.
+		startIdx := 0
+		for i, pt := range pts {
+			if pt.onCurve {
+				startIdx = i
+				break
+			}
+		}
+		pts = append(pts[startIdx:], pts[:startIdx]...)
+
+		toPx := func(pt point) (float64, float64) {
+			return pt.x * scale, baseline - pt.y*scale
+		}
+		px, py := toPx(pts[0])
+		for i := 1; i <= len(pts); i++ {
+			pt := pts[i%len(pts)]
+			if pt.onCurve {
+				x, y := toPx(pt)
+				segs = append(segs, segment{px, py, x, y})
+				px, py = x, y
+				continue
+			}
+			end := pts[(i+1)%len(pts)]
+			cx, cy := toPx(pt)
+			ex, ey := toPx(end)
+			const steps = 8
+			for s := 1; s <= steps; s++ {
+				t := float64(s) / steps
+				mt := 1 - t
+				x := mt*mt*px + 2*mt*t*cx + t*t*ex
+				y := mt*mt*py + 2*mt*t*cy + t*t*ey
+				segs = append(segs, segment{px, py, x, y})
+				px, py = x, y
+			}
+			i++ // the end point was consumed
+		}
+	}
+	return segs
+}
+
+// rasterize renders contours into a cellWidth×cellHeight alpha map using
+// supersampled nonzero-winding scanline coverage.
+func rasterize(contours [][]point, scale, baseline float64) []byte {
+	segs := flatten(contours, scale, baseline)
+	alpha := make([]byte, cellWidth*cellHeight)
+	if len(segs) == 0 {
+		return alpha
+	}
+
+	for py := 0; py < cellHeight; py++ {
+		for px := 0; px < cellWidth; px++ {
+			covered := 0
+			for sy := 0; sy < supersample; sy++ {
+				y := float64(py) + (float64(sy)+0.5)/supersample
+				for sx := 0; sx < supersample; sx++ {
+					x := float64(px) + (float64(sx)+0.5)/supersample
+					if winding(segs, x, y) != 0 {
+						covered++
+					}
+				}
+			}
+			alpha[py*cellWidth+px] = byte(math.Round(float64(covered) * 255 / (supersample * supersample)))
+		}
+	}
+	return alpha
+}
+
+// winding returns the nonzero winding number of (x, y) using a ray cast toward +x.
+func winding(segs []segment, x, y float64) int {
+	w := 0
+	for _, s := range segs {
+		if (s.y0 <= y) == (s.y1 <= y) {
+			continue
+		}
+		t := (y - s.y0) / (s.y1 - s.y0)
+		if s.x0+t*(s.x1-s.x0) > x {
+			if s.y1 > s.y0 {
+				w++
+			} else {
+				w--
+			}
+		}
+	}
+	return w
+}
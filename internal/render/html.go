@@ -0,0 +1,86 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+)
+
+// HTML renders a screen as a standalone HTML document. Colors and attributes
+// become inline-styled spans; the cursor is drawn as a reverse-video cell.
+func HTML(s *Screen, title string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { margin: 0; background: #%02x%02x%02x; }
+pre { margin: 0; padding: 8px; color: #%02x%02x%02x; background: #%02x%02x%02x; font: 14px/1.2 "DejaVu Sans Mono", Menlo, Consolas, monospace; }
+</style>
+</head>
+<body>
+<pre>`, html.EscapeString(title),
+		ansi.DefaultBG[0], ansi.DefaultBG[1], ansi.DefaultBG[2],
+		ansi.DefaultFG[0], ansi.DefaultFG[1], ansi.DefaultFG[2],
+		ansi.DefaultBG[0], ansi.DefaultBG[1], ansi.DefaultBG[2])
+
+	for y, row := range s.Cells {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+
+		var run []rune
+		var runStyle ansi.Style
+		flush := func() {
+			if len(run) == 0 {
+				return
+			}
+			text := html.EscapeString(string(run))
+			if css := runStyle.CSS(); css != "" {
+				fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, text)
+			} else {
+				b.WriteString(text)
+			}
+			run = run[:0]
+		}
+
+		for x, cell := range row[:contentWidth(s, y)] {
+			if cell.Cont {
+				continue
+			}
+			st := cell.Style
+			if s.CursorVisible && x == s.CursorX && y == s.CursorY {
+				st.Reverse = !st.Reverse
+			}
+			if st != runStyle {
+				flush()
+				runStyle = st
+			}
+			run = append(run, cell.Rune)
+		}
+		flush()
+	}
+
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// contentWidth returns the number of leading cells of row y worth emitting:
+// trailing default-styled blanks are dropped unless the cursor sits on them.
+func contentWidth(s *Screen, y int) int {
+	row := s.Cells[y]
+	n := len(row)
+	for n > 0 {
+		cell := row[n-1]
+		isCursor := s.CursorVisible && s.CursorY == y && s.CursorX == n-1
+		if cell.Rune != ' ' || cell.Style != (ansi.Style{}) || isCursor {
+			break
+		}
+		n--
+	}
+	return n
+}
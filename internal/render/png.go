@@ -0,0 +1,130 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+)
+
+const imagePadding = 8
+
+// PNG renders a screen as a PNG image using the embedded bitmap font.
+func PNG(w io.Writer, s *Screen) error {
+	font, err := loadFont()
+	if err != nil {
+		return err
+	}
+
+	cw, ch := font.cellWidth, font.cellHeight
+	img := image.NewRGBA(image.Rect(0, 0, s.Cols*cw+2*imagePadding, s.Rows*ch+2*imagePadding))
+	fill(img, img.Bounds(), rgb(ansi.DefaultBG))
+
+	for y, row := range s.Cells {
+		for x, cell := range row {
+			if cell.Cont {
+				continue
+			}
+			st := cell.Style
+			if s.CursorVisible && x == s.CursorX && y == s.CursorY {
+				st.Reverse = !st.Reverse
+			}
+			fg, bg := cellColors(st)
+
+			width := cw
+			if cell.Wide {
+				width = 2 * cw
+			}
+			x0, y0 := imagePadding+x*cw, imagePadding+y*ch
+			fill(img, image.Rect(x0, y0, x0+width, y0+ch), bg)
+
+			if cell.Rune != ' ' {
+				glyph, ok := font.glyphs[cell.Rune]
+				if ok {
+					drawGlyph(img, glyph, x0, y0, cw, ch, fg)
+					if st.Bold {
+						drawGlyph(img, glyph, x0+1, y0, cw, ch, fg)
+					}
+				} else {
+					// Missing glyph: hollow box
+					strokeRect(img, image.Rect(x0+1, y0+2, x0+width-1, y0+ch-2), fg)
+				}
+			}
+			if st.Underline {
+				fill(img, image.Rect(x0, y0+ch-3, x0+width, y0+ch-2), fg)
+			}
+			if st.Strike {
+				fill(img, image.Rect(x0, y0+ch/2, x0+width, y0+ch/2+1), fg)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// cellColors resolves a style to concrete foreground and background colors,
+// applying reverse video and dim.
+func cellColors(st ansi.Style) (fg, bg color.RGBA) {
+	fg, bg = rgb(ansi.DefaultFG), rgb(ansi.DefaultBG)
+	if r, g, b, ok := st.FG.RGB(); ok {
+		fg = color.RGBA{R: r, G: g, B: b, A: 0xff}
+	}
+	if r, g, b, ok := st.BG.RGB(); ok {
+		bg = color.RGBA{R: r, G: g, B: b, A: 0xff}
+	}
+	if st.Reverse {
+		fg, bg = bg, fg
+	}
+	if st.Dim {
+		fg = blend(bg, fg, 0x99)
+	}
+	return fg, bg
+}
+
+func rgb(c [3]uint8) color.RGBA {
+	return color.RGBA{R: c[0], G: c[1], B: c[2], A: 0xff}
+}
+
+// blend mixes src over dst with the given alpha (0–255).
+func blend(dst, src color.RGBA, alpha uint8) color.RGBA {
+	a := uint32(alpha)
+	mix := func(d, s uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a) + 127) / 255)
+	}
+	return color.RGBA{R: mix(dst.R, src.R), G: mix(dst.G, src.G), B: mix(dst.B, src.B), A: 0xff}
+}
+
+func drawGlyph(img *image.RGBA, glyph []byte, x0, y0, cw, ch int, fg color.RGBA) {
+	bounds := img.Bounds()
+	for gy := 0; gy < ch; gy++ {
+		for gx := 0; gx < cw; gx++ {
+			alpha := glyph[gy*cw+gx]
+			if alpha == 0 {
+				continue
+			}
+			px, py := x0+gx, y0+gy
+			if !(image.Point{X: px, Y: py}).In(bounds) {
+				continue
+			}
+			img.SetRGBA(px, py, blend(img.RGBAAt(px, py), fg, alpha))
+		}
+	}
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func strokeRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	fill(img, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), c)
+	fill(img, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
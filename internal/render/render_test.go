@@ -0,0 +1,81 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+)
+
+func TestParseScreen(t *testing.T) {
+	s := ParseScreen("ab\x1b[31mc\x1b[0m\tx\n日z\ntoo long line", 10, 3)
+
+	if s.Cells[0][0].Rune != 'a' || s.Cells[0][2].Rune != 'c' {
+		t.Fatalf("row 0 = %+v, want a b c", s.Cells[0][:3])
+	}
+	if s.Cells[0][2].Style.FG != (ansi.Color{Kind: ansi.ColorIndexed, Index: 1}) {
+		t.Fatalf("cell (2,0) style = %+v, want red", s.Cells[0][2].Style)
+	}
+	if s.Cells[0][8].Rune != 'x' {
+		t.Fatalf("cell (8,0) = %q, want tab-expanded x", s.Cells[0][8].Rune)
+	}
+	if !s.Cells[1][0].Wide || !s.Cells[1][1].Cont || s.Cells[1][2].Rune != 'z' {
+		t.Fatalf("row 1 = %+v, want wide rune followed by z", s.Cells[1][:3])
+	}
+	if got := string([]rune{s.Cells[2][0].Rune, s.Cells[2][9].Rune}); got != "tl" {
+		t.Fatalf("row 2 ends = %q, want truncated line", got)
+	}
+}
+
+func TestParseScreenPadsMissingRows(t *testing.T) {
+	s := ParseScreen("only", 4, 3)
+	if len(s.Cells) != 3 || len(s.Cells[2]) != 4 || s.Cells[2][0].Rune != ' ' {
+		t.Fatalf("expected blank padded rows, got %+v", s.Cells)
+	}
+}
+
+func TestHTMLMarksCursorAndEscapes(t *testing.T) {
+	s := ParseScreen("<b>\x1b[1mok", 6, 1)
+	s.CursorX, s.CursorY, s.CursorVisible = 0, 0, true
+
+	out := string(HTML(s, "hq-mayor"))
+	if !strings.Contains(out, "<title>hq-mayor</title>") {
+		t.Fatalf("missing title in %q", out)
+	}
+	if !strings.Contains(out, `<span style="color:#000000;background-color:#e5e5e5">&lt;</span>b&gt;`) {
+		t.Fatalf("missing reverse-video cursor cell in %q", out)
+	}
+	if !strings.Contains(out, `<span style="font-weight:bold">ok</span>`) {
+		t.Fatalf("missing bold span in %q", out)
+	}
+}
+
+func TestPNGDimensions(t *testing.T) {
+	font, err := loadFont()
+	if err != nil {
+		t.Fatalf("loadFont() error = %v", err)
+	}
+	if _, ok := font.glyphs['A']; !ok {
+		t.Fatal("font atlas missing 'A'")
+	}
+	if _, ok := font.glyphs['─']; !ok {
+		t.Fatal("font atlas missing box drawing glyphs")
+	}
+
+	s := ParseScreen("hello \x1b[7mworld\x1b[0m ✓ 日", 20, 2)
+	var buf bytes.Buffer
+	if err := PNG(&buf, s); err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	wantW := 20*font.cellWidth + 2*imagePadding
+	wantH := 2*font.cellHeight + 2*imagePadding
+	if b := img.Bounds(); b.Dx() != wantW || b.Dy() != wantH {
+		t.Fatalf("image size = %dx%d, want %dx%d", b.Dx(), b.Dy(), wantW, wantH)
+	}
+}
@@ -0,0 +1,97 @@
+// Package render turns captured tmux screens into standalone HTML documents
+// and PNG images without a browser or external font dependencies.
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+)
+
+// Cell is one character cell of a terminal screen.
+type Cell struct {
+	Rune  rune
+	Style ansi.Style
+	Wide  bool // rune occupies this cell and the next
+	Cont  bool // right half of a wide rune; nothing to draw
+}
+
+// Screen is a fixed-size grid of styled cells plus cursor state.
+type Screen struct {
+	Cols, Rows    int
+	Cells         [][]Cell
+	CursorX       int
+	CursorY       int
+	CursorVisible bool
+}
+
+// ParseScreen lays out capture-pane -e output on a cols×rows grid.
+// Lines longer than cols are truncated; missing cells are blank.
+func ParseScreen(content string, cols, rows int) *Screen {
+	s := &Screen{Cols: cols, Rows: rows, Cells: make([][]Cell, rows)}
+	for y := range s.Cells {
+		row := make([]Cell, cols)
+		for x := range row {
+			row[x] = Cell{Rune: ' '}
+		}
+		s.Cells[y] = row
+	}
+
+	var st ansi.Style
+	for y, line := range strings.Split(content, "\n") {
+		if y >= rows {
+			break
+		}
+		var segs []ansi.Segment
+		segs, st = ansi.Segments(line, st)
+
+		x := 0
+		for _, seg := range segs {
+			for _, r := range seg.Text {
+				if r == utf8.RuneError {
+					r = '?'
+				}
+				if r == '\t' {
+					next := min((x/8+1)*8, cols)
+					for ; x < next; x++ {
+						s.Cells[y][x] = Cell{Rune: ' ', Style: seg.Style}
+					}
+					continue
+				}
+				w := runeWidth(r)
+				if x+w > cols {
+					break
+				}
+				s.Cells[y][x] = Cell{Rune: r, Style: seg.Style, Wide: w == 2}
+				if w == 2 {
+					s.Cells[y][x+1] = Cell{Rune: ' ', Style: seg.Style, Cont: true}
+				}
+				x += w
+			}
+		}
+	}
+	return s
+}
+
+// runeWidth returns the number of terminal columns a rune occupies (1 or 2).
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0x303e, // CJK radicals, punctuation
+		r >= 0x3041 && r <= 0x33ff, // Kana, CJK symbols
+		r >= 0x3400 && r <= 0x4dbf, // CJK extension A
+		r >= 0x4e00 && r <= 0x9fff, // CJK unified ideographs
+		r >= 0xa000 && r <= 0xa4cf, // Yi
+		r >= 0xac00 && r <= 0xd7a3, // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff, // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f, // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60, // Fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // Emoji and pictographs
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/audit"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// recordAudit records a mutating action by the request's identity.
+func (h *Handler) recordAudit(r *http.Request, action, agent string, detail map[string]any, err error) {
+	identity, _ := auth.IdentityFrom(r.Context())
+	result, errMsg := audit.Outcome(err)
+	h.audit.Record(audit.Entry{
+		Actor:  identity.Name,
+		Issuer: identity.Issuer,
+		Remote: r.RemoteAddr,
+		Action: action,
+		Agent:  agent,
+		Detail: detail,
+		Result: result,
+		Error:  errMsg,
+	})
+}
+
+// auditDenied records a mutating action rejected by access control.
+func (h *Handler) auditDenied(r *http.Request, action, agent, reason string) {
+	identity, _ := auth.IdentityFrom(r.Context())
+	h.audit.Record(audit.Entry{
+		Actor:  identity.Name,
+		Issuer: identity.Issuer,
+		Remote: r.RemoteAddr,
+		Action: action,
+		Agent:  agent,
+		Result: audit.ResultDenied,
+		Error:  reason,
+	})
+}
+
+// handleAudit handles GET /api/audit?actor=&agent=&action=&since=&until=&limit= —
+// query the audit log, newest first.
+func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authorize(w, r, auth.ScopeAdmin)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	if h.audit == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "audit log is disabled"})
+		return
+	}
+
+	filter, err := parseAuditFilter(r, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if identity.Restricted() {
+		filter.Visible = h.visibleByName(identity)
+	}
+
+	entries, err := h.audit.Query(filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": entries})
+}
+
+func parseAuditFilter(r *http.Request, now time.Time) (audit.Filter, error) {
+	params := r.URL.Query()
+	f := audit.Filter{
+		Actor:  strings.TrimSpace(params.Get("actor")),
+		Agent:  strings.TrimSpace(params.Get("agent")),
+		Action: strings.TrimSpace(params.Get("action")),
+	}
+	var err error
+	if f.Since, err = timeParam(params.Get("since"), now); err != nil {
+		return f, fmt.Errorf("invalid since: %v", err)
+	}
+	if f.Until, err = timeParam(params.Get("until"), now); err != nil {
+		return f, fmt.Errorf("invalid until: %v", err)
+	}
+	if f.Limit, err = intParam(params.Get("limit"), defaultAuditLimit, 1, maxAuditLimit); err != nil {
+		return f, fmt.Errorf("invalid limit: %v", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+const (
+	defaultCrashLimit = 50
+	maxCrashLimit     = 500
+)
+
+// handleCrashes handles GET /api/crashes?agent=&limit= — recent agent
+// crashes, newest first.
+func (h *Handler) handleCrashes(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authorize(w, r, auth.ScopeRead)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	limit, err := intParam(r.URL.Query().Get("limit"), defaultCrashLimit, 1, maxCrashLimit)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid limit: " + err.Error()})
+		return
+	}
+
+	agent := r.URL.Query().Get("agent")
+	visible := h.visibleByName(identity)
+	crashes := h.registry.Crashes(func(c agents.Crash) bool {
+		return (agent == "" || c.Agent == agent) && visible(c.Agent)
+	}, limit)
+	writeJSON(w, http.StatusOK, map[string]any{"crashes": crashes})
+}
@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
+)
+
+// getEnv handles GET /api/agents/{name}/env — the session environment, with
+// secret values redacted.
+func (h *Handler) getEnv(w http.ResponseWriter, _ *http.Request, name string) {
+	if _, ok := h.registry.GetAgent(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+	env, redacted, err := h.life.Env(name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"env": env, "redacted": redacted})
+}
+
+// setEnv handles PUT /api/agents/{name}/env/{key} with {"value":"..."}.
+func (h *Handler) setEnv(w http.ResponseWriter, r *http.Request, name, key string) {
+	if _, ok := h.registry.GetAgent(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+	var req struct {
+		Value *string `json:"value"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || req.Value == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": `body must be {"value":"..."}`})
+		return
+	}
+
+	err := h.life.SetEnv(name, key, *req.Value)
+	detail := map[string]any{"key": key, "value": *req.Value}
+	if lifecycle.IsSecretEnv(key) {
+		detail["value"] = lifecycle.RedactedValue
+	}
+	h.recordAudit(r, "env", name, detail, err)
+	if err != nil {
+		writeJSON(w, envStatus(err), map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// unsetEnv handles DELETE /api/agents/{name}/env/{key}.
+func (h *Handler) unsetEnv(w http.ResponseWriter, r *http.Request, name, key string) {
+	if _, ok := h.registry.GetAgent(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+	err := h.life.UnsetEnv(name, key)
+	h.recordAudit(r, "env", name, map[string]any{"key": key, "unset": true}, err)
+	if err != nil {
+		writeJSON(w, envStatus(err), map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func envStatus(err error) int {
+	if errors.Is(err, lifecycle.ErrInvalidEnv) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+func TestEnvWritesRequireAdmin(t *testing.T) {
+	authn, err := auth.NewAuthenticator("", []auth.Token{
+		{Name: "ops", Token: "tok-ops", Scope: auth.ScopeOperate},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	New(Options{Registry: agents.NewRegistry(nil, "", agents.DiscoveryRules{}), Authn: authn}).Register(mux)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/api/agents/hq-mayor/env/LD_PRELOAD", strings.NewReader(`{"value":"/tmp/evil.so"}`))
+		req.Header.Set("Authorization", "Bearer tok-ops")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s env as operate = %d, want 403", method, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+// feedEntry is one Server-Sent Event retained for replay.
+type feedEntry struct {
+	id    uint64
+	event string
+	agent *auth.AgentRef // agent the entry is about, for ACL filtering (nil = feed-wide)
+	data  []byte
+}
+
+// feed is a fan-out of SSE entries with a bounded replay ring, so clients can
+// resume with Last-Event-ID after a reconnect. IDs are "<epoch>-<seq>"; the
+// epoch changes when the adapter restarts so stale IDs are detected as gaps.
+type feed struct {
+	epoch       string
+	mu          sync.Mutex
+	ring        []feedEntry
+	ringSize    int
+	nextID      uint64
+	subscribers map[chan feedEntry]struct{}
+	closed      bool
+}
+
+func newFeed(epoch string, ringSize int) *feed {
+	return &feed{
+		epoch:       epoch,
+		ringSize:    ringSize,
+		nextID:      1,
+		subscribers: make(map[chan feedEntry]struct{}),
+	}
+}
+
+// publish appends an entry and delivers it to all subscribers. A subscriber
+// that cannot keep up is disconnected; it can resume from the ring.
+func (f *feed) publish(event string, agent *auth.AgentRef, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+
+	e := feedEntry{id: f.nextID, event: event, agent: agent, data: data}
+	f.nextID++
+	f.ring = append(f.ring, e)
+	if len(f.ring) > f.ringSize {
+		f.ring = f.ring[len(f.ring)-f.ringSize:]
+	}
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers a new subscriber. If lastEventID is non-empty, entries
+// after it are returned as backlog; gap reports that some were already evicted
+// (or the ID belongs to another epoch).
+func (f *feed) subscribe(lastEventID string) (ch chan feedEntry, backlog []feedEntry, gap bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch = make(chan feedEntry, 256)
+	if f.closed {
+		close(ch)
+		return ch, nil, false
+	}
+	f.subscribers[ch] = struct{}{}
+
+	if lastEventID == "" {
+		return ch, nil, false
+	}
+	seq, ok := f.parseID(lastEventID)
+	if !ok {
+		return ch, append([]feedEntry(nil), f.ring...), true
+	}
+	for _, e := range f.ring {
+		if e.id > seq {
+			backlog = append(backlog, e)
+		}
+	}
+	oldest := f.nextID
+	if len(f.ring) > 0 {
+		oldest = f.ring[0].id
+	}
+	return ch, backlog, seq+1 < oldest
+}
+
+// unsubscribe removes a subscriber and reports how many remain.
+func (f *feed) unsubscribe(ch chan feedEntry) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+	return len(f.subscribers)
+}
+
+// close disconnects all subscribers; later publishes are ignored.
+func (f *feed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (f *feed) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func (f *feed) formatID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", f.epoch, seq)
+}
+
+func (f *feed) parseID(id string) (uint64, bool) {
+	epoch, seqStr, ok := strings.Cut(strings.TrimSpace(id), "-")
+	if !ok || epoch != f.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
@@ -0,0 +1,87 @@
+package rest
+
+import "testing"
+
+func TestFeedResumeFromLastEventID(t *testing.T) {
+	f := newFeed("e1", 4)
+	for _, d := range []string{"a", "b", "c"} {
+		f.publish("output", nil, []byte(d))
+	}
+
+	ch, backlog, gap := f.subscribe("e1-1")
+	defer f.unsubscribe(ch)
+	if gap {
+		t.Fatal("gap = true, want false")
+	}
+	if len(backlog) != 2 || string(backlog[0].data) != "b" || string(backlog[1].data) != "c" {
+		t.Fatalf("backlog = %+v, want b, c", backlog)
+	}
+
+	f.publish("output", nil, []byte("d"))
+	if e := <-ch; string(e.data) != "d" || f.formatID(e.id) != "e1-4" {
+		t.Fatalf("live entry = %+v, want d with id e1-4", e)
+	}
+}
+
+func TestFeedReportsGap(t *testing.T) {
+	f := newFeed("e1", 2)
+	for _, d := range []string{"a", "b", "c", "d"} {
+		f.publish("output", nil, []byte(d))
+	}
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "evicted", id: "e1-1"},
+		{name: "other epoch", id: "e0-3"},
+		{name: "malformed", id: "garbage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, backlog, gap := f.subscribe(tt.id)
+			defer f.unsubscribe(ch)
+			if !gap {
+				t.Fatal("gap = false, want true")
+			}
+			if len(backlog) != 2 {
+				t.Fatalf("backlog len = %d, want 2 (whole ring)", len(backlog))
+			}
+		})
+	}
+}
+
+func TestFeedDisconnectsSlowSubscriber(t *testing.T) {
+	f := newFeed("e1", 1000)
+	slow, _, _ := f.subscribe("")
+	for i := 0; i < cap(slow)+1; i++ {
+		f.publish("output", nil, []byte("x"))
+	}
+
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != cap(slow) {
+		t.Fatalf("received %d entries before close, want %d", n, cap(slow))
+	}
+	if remaining := f.unsubscribe(slow); remaining != 0 {
+		t.Fatalf("remaining subscribers = %d, want 0", remaining)
+	}
+}
+
+func TestFeedCloseEndsSubscribers(t *testing.T) {
+	f := newFeed("e1", 4)
+	ch, _, _ := f.subscribe("")
+	f.close()
+	if _, ok := <-ch; ok {
+		t.Fatal("subscriber channel still open after close")
+	}
+	if !f.isClosed() {
+		t.Fatal("isClosed() = false after close")
+	}
+	late, _, _ := f.subscribe("")
+	if _, ok := <-late; ok {
+		t.Fatal("subscribe after close returned an open channel")
+	}
+}
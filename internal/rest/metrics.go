@@ -0,0 +1,27 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
+)
+
+// handleMetrics handles GET /api/metrics — operational counters.
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r, auth.ScopeAdmin); !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rateLimited": h.limits.Rejected()})
+}
+
+// writeRateLimited writes a 429 response with a Retry-After hint.
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", ratelimit.RetryAfter(wait))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": "rate limit exceeded"})
+}
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
+)
+
+func TestAuthenticateRateLimits(t *testing.T) {
+	authn, err := auth.NewAuthenticator("", []auth.Token{{Name: "ops", Token: "otok", Scope: auth.ScopeOperate}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{authn: authn, limits: ratelimit.New(ratelimit.Config{
+		AuthFailures: ratelimit.Rate{PerSecond: 0.01, Burst: 1},
+		Scopes: map[auth.Scope]ratelimit.Limits{
+			auth.ScopeOperate: {REST: ratelimit.Rate{PerSecond: 0.01, Burst: 1}},
+		},
+	})}
+
+	call := func(token, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/agents", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.authenticate(rec, req)
+		return rec
+	}
+
+	if rec := call("otok", "10.0.0.1:1"); rec.Code != http.StatusOK {
+		t.Fatalf("first call = %d", rec.Code)
+	}
+	rec := call("otok", "10.0.0.1:1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second call = %d (Retry-After %q), want 429", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	if rec := call("guess", "10.0.0.9:1"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token = %d, want 401", rec.Code)
+	}
+	if rec := call("otok", "10.0.0.9:2"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("after failed auth = %d, want 429 even with a valid token", rec.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/gastownhall/tmux-adapter/internal/render"
+)
+
+// renderScreenHTML handles GET /api/agents/{name}/screen.html.
+func (h *Handler) renderScreenHTML(w http.ResponseWriter, _ *http.Request, name string) {
+	screen, ok := h.captureRenderableScreen(w, name)
+	if !ok {
+		return
+	}
+	writeBody(w, "text/html; charset=utf-8", render.HTML(screen, name))
+}
+
+// renderScreenPNG handles GET /api/agents/{name}/screen.png.
+func (h *Handler) renderScreenPNG(w http.ResponseWriter, _ *http.Request, name string) {
+	screen, ok := h.captureRenderableScreen(w, name)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := render.PNG(&buf, screen); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeBody(w, "image/png", buf.Bytes())
+}
+
+// captureRenderableScreen captures the visible pane and cursor state of an agent.
+// On failure it writes the error response and returns ok=false.
+func (h *Handler) captureRenderableScreen(w http.ResponseWriter, name string) (*render.Screen, bool) {
+	agent, ok := h.registry.GetAgent(name)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return nil, false
+	}
+
+	cursor, err := h.ctrl.GetCursorInfo(agent.Target())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil, false
+	}
+	content, err := h.ctrl.CapturePaneVisible(agent.Target())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil, false
+	}
+
+	screen := render.ParseScreen(content, cursor.Width, cursor.Height)
+	screen.CursorX, screen.CursorY, screen.CursorVisible = cursor.X, cursor.Y, cursor.Visible
+	return screen, true
+}
+
+func writeBody(w http.ResponseWriter, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("write response: %v", err)
+	}
+}
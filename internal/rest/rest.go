@@ -3,30 +3,74 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/audit"
 	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/history"
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
 	"github.com/gastownhall/tmux-adapter/internal/nudge"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
 	"github.com/gastownhall/tmux-adapter/internal/tmux"
 )
 
 // Handler provides REST API endpoints for agent management.
 type Handler struct {
-	registry  *agents.Registry
-	ctrl      *tmux.ControlMode
-	authToken string
+	registry *agents.Registry
+	ctrl     *tmux.ControlMode
+	life     *lifecycle.Manager
+	authn    *auth.Authenticator
+	history  *history.Store          // nil when transcript recording is disabled
+	outputs  *outputStreams          // SSE output feeds per agent
+	events   *feed                   // SSE lifecycle event feed
+	audit    *audit.Logger           // nil when auditing is disabled
+	limits   *ratelimit.Limiters     // nil when rate limiting is disabled
+	watched  func(agent string) bool // reports web viewers; nil = never count them as attached
+	locked   func(agent string) (holder string, ok bool)
+}
+
+// Options configures a Handler.
+type Options struct {
+	Registry *agents.Registry
+	Ctrl     *tmux.ControlMode
+	Life     *lifecycle.Manager
+	PipeMgr  *tmux.PipePaneManager
+	Authn    *auth.Authenticator
+	History  *history.Store      // nil disables transcript search
+	Audit    *audit.Logger       // nil disables auditing
+	Limits   *ratelimit.Limiters // nil disables rate limiting
+
+	// Watched reports agents streamed by web viewers, which count as
+	// attached for prompt delivery; nil never counts them.
+	Watched func(agent string) bool
+	// InputLock reports who holds an agent's WebSocket input lock; prompts
+	// from anyone else are refused. nil never locks.
+	InputLock func(agent string) (holder string, ok bool)
 }
 
 // New creates a new REST Handler.
-func New(registry *agents.Registry, ctrl *tmux.ControlMode, authToken string) *Handler {
+func New(opts Options) *Handler {
+	epoch := strconv.FormatInt(time.Now().Unix(), 36)
 	return &Handler{
-		registry:  registry,
-		ctrl:      ctrl,
-		authToken: authToken,
+		registry: opts.Registry,
+		ctrl:     opts.Ctrl,
+		life:     opts.Life,
+		authn:    opts.Authn,
+		history:  opts.History,
+		outputs:  newOutputStreams(opts.PipeMgr, epoch),
+		events:   newFeed(epoch, eventRingSize),
+		audit:    opts.Audit,
+		limits:   opts.Limits,
+		watched:  opts.Watched,
+		locked:   opts.InputLock,
 	}
 }
 
@@ -34,27 +78,39 @@ func New(registry *agents.Registry, ctrl *tmux.ControlMode, authToken string) *H
 func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/agents", h.handleAgents)
 	mux.HandleFunc("/api/agents/", h.handleAgentByName)
+	mux.HandleFunc("/api/search", h.handleSearch)
+	mux.HandleFunc("/api/events", h.streamEvents)
+	mux.HandleFunc("/api/tokens", h.mintToken)
+	mux.HandleFunc("/api/crashes", h.handleCrashes)
+	mux.HandleFunc("/api/audit", h.handleAudit)
+	mux.HandleFunc("/api/metrics", h.handleMetrics)
 }
 
-// handleAgents handles GET /api/agents — list all agents.
+// handleAgents handles GET /api/agents — list all agents — and
+// POST /api/agents — spawn one.
 func (h *Handler) handleAgents(w http.ResponseWriter, r *http.Request) {
-	if !auth.IsAuthorizedRequest(h.authToken, r) {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+	identity, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
-	if r.Method != http.MethodGet {
+	switch r.Method {
+	case http.MethodGet:
+		if !requireScope(w, identity.Scope, auth.ScopeRead) {
+			return
+		}
+		visible := auth.VisibleAgents(identity, h.registry.GetAgents())
+		writeJSON(w, http.StatusOK, map[string]any{"agents": visible})
+	case http.MethodPost:
+		h.spawnAgent(w, r.WithContext(auth.WithIdentity(r.Context(), identity)), identity)
+	default:
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
-		return
 	}
-
-	all := h.registry.GetAgents()
-	writeJSON(w, http.StatusOK, map[string]any{"agents": all})
 }
 
 // handleAgentByName routes /api/agents/{name} and /api/agents/{name}/... sub-paths.
 func (h *Handler) handleAgentByName(w http.ResponseWriter, r *http.Request) {
-	if !auth.IsAuthorizedRequest(h.authToken, r) {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+	identity, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -74,18 +130,123 @@ func (h *Handler) handleAgentByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var scope auth.Scope
+	var action string // audit action for mutating routes
+	var serve func(http.ResponseWriter, *http.Request, string)
 	switch {
 	case sub == "" && r.Method == http.MethodGet:
-		h.getAgent(w, r, name)
+		scope, serve = auth.ScopeRead, h.getAgent
 	case sub == "" && r.Method == http.MethodDelete:
-		h.killAgent(w, r, name)
+		scope, action, serve = auth.ScopeAdmin, "kill", h.killAgent
+	case sub == "restart" && r.Method == http.MethodPost:
+		scope, action, serve = auth.ScopeAdmin, "restart", h.restartAgent
+	case sub == "restart-policy" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.getRestartPolicy
+	case sub == "restart-policy" && r.Method == http.MethodPut:
+		scope, action, serve = auth.ScopeAdmin, "restart-policy", h.setRestartPolicy
+	case sub == "restart-policy" && r.Method == http.MethodDelete:
+		scope, action, serve = auth.ScopeAdmin, "restart-policy", h.clearRestartPolicy
+	case sub == "env" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.getEnv
+	case strings.HasPrefix(sub, "env/") && r.Method == http.MethodPut:
+		// Admin only: variables such as LD_PRELOAD or PATH run code on
+		// the agent's next restart.
+		key := strings.TrimPrefix(sub, "env/")
+		scope, action = auth.ScopeAdmin, "env"
+		serve = func(w http.ResponseWriter, r *http.Request, name string) { h.setEnv(w, r, name, key) }
+	case strings.HasPrefix(sub, "env/") && r.Method == http.MethodDelete:
+		key := strings.TrimPrefix(sub, "env/")
+		scope, action = auth.ScopeAdmin, "env"
+		serve = func(w http.ResponseWriter, r *http.Request, name string) { h.unsetEnv(w, r, name, key) }
 	case sub == "prompt" && r.Method == http.MethodPost:
-		h.sendPrompt(w, r, name)
+		scope, action, serve = auth.ScopeOperate, "prompt", h.sendPrompt
 	case sub == "screen" && r.Method == http.MethodGet:
-		h.captureScreen(w, r, name)
+		scope, serve = auth.ScopeRead, h.captureScreen
+	case sub == "screen.html" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.renderScreenHTML
+	case sub == "screen.png" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.renderScreenPNG
+	case sub == "output/stream" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.streamOutput
+	case sub == "history" && r.Method == http.MethodGet:
+		scope, serve = auth.ScopeRead, h.captureHistory
 	default:
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+		return
+	}
+
+	r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+	// Agents that are not running, e.g. in a restart backoff, are checked by
+	// their session name.
+	ref := auth.SessionRef(name)
+	if agent, ok := h.registry.GetAgent(name); ok {
+		ref = auth.RefOf(agent)
 	}
+	granted := identity.ScopeFor(ref)
+	// Agents outside the caller's ACL are reported exactly like missing ones.
+	if identity.Scope.Allows(scope) && !granted.Allows(auth.ScopeRead) {
+		if action != "" {
+			h.auditDenied(r, action, name, "agent not visible")
+		}
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+	if !requireScope(w, granted, scope) {
+		if action != "" {
+			h.auditDenied(r, action, name, "requires "+scope.String()+" scope")
+		}
+		return
+	}
+	serve(w, r, name)
+}
+
+// visibleByName returns a visibility check by agent name for records that may
+// outlive the agent (transcripts, audit entries).
+func (h *Handler) visibleByName(identity auth.Identity) func(string) bool {
+	return func(name string) bool {
+		if agent, ok := h.registry.GetAgent(name); ok {
+			return identity.CanSee(auth.RefOf(agent))
+		}
+		return identity.CanSee(auth.SessionRef(name))
+	}
+}
+
+// authenticate resolves the caller and charges the request to its rate
+// limit, writing a 401 or 429 response on failure.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	if blocked, wait := h.limits.AuthBlocked(r); blocked {
+		writeRateLimited(w, wait)
+		return auth.Identity{}, false
+	}
+	identity, ok := h.authn.Authenticate(r)
+	if !ok {
+		h.limits.AuthFailed(r)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return identity, false
+	}
+	if allowed, wait := h.limits.Allow(ratelimit.KindREST, identity, r.RemoteAddr); !allowed {
+		writeRateLimited(w, wait)
+		return identity, false
+	}
+	return identity, true
+}
+
+// authorize authenticates the caller and checks it holds the required scope.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, scope auth.Scope) (auth.Identity, bool) {
+	identity, ok := h.authenticate(w, r)
+	if !ok || !requireScope(w, identity.Scope, scope) {
+		return identity, false
+	}
+	return identity, true
+}
+
+// requireScope writes a 403 response if the granted scope is insufficient.
+func requireScope(w http.ResponseWriter, granted, scope auth.Scope) bool {
+	if granted.Allows(scope) {
+		return true
+	}
+	writeJSON(w, http.StatusForbidden, map[string]any{"error": "forbidden: requires " + scope.String() + " scope"})
+	return false
 }
 
 // getAgent handles GET /api/agents/{name}.
@@ -95,7 +256,7 @@ func (h *Handler) getAgent(w http.ResponseWriter, _ *http.Request, name string)
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"agent": agent})
+	writeJSON(w, http.StatusOK, map[string]any{"agent": agent, "supervisor": h.life.Supervision(name)})
 }
 
 // sendPrompt handles POST /api/agents/{name}/prompt.
@@ -120,11 +281,25 @@ func (h *Handler) sendPrompt(w http.ResponseWriter, r *http.Request, name string
 		return
 	}
 
+	if h.locked != nil {
+		if holder, ok := h.locked(name); ok {
+			reason := fmt.Sprintf("input to %s is locked by %s", name, holder)
+			h.auditDenied(r, "prompt", name, reason)
+			writeJSON(w, http.StatusConflict, map[string]any{"error": reason, "holder": holder})
+			return
+		}
+	}
+
 	mu := nudge.GetLock(name)
 	mu.Lock()
 	defer mu.Unlock()
 
-	if err := nudge.Session(h.ctrl, agent, payload.Prompt); err != nil {
+	if h.watched != nil && h.watched(name) {
+		agent.Attached = true // someone is watching; no need to wake the pane
+	}
+	err = nudge.Session(h.ctrl, agent, payload.Prompt)
+	h.recordAudit(r, "prompt", name, audit.PromptDetail(payload.Prompt), err)
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
@@ -134,12 +309,13 @@ func (h *Handler) sendPrompt(w http.ResponseWriter, r *http.Request, name string
 
 // captureScreen handles GET /api/agents/{name}/screen.
 func (h *Handler) captureScreen(w http.ResponseWriter, _ *http.Request, name string) {
-	if _, ok := h.registry.GetAgent(name); !ok {
+	agent, ok := h.registry.GetAgent(name)
+	if !ok {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
 		return
 	}
 
-	content, err := h.ctrl.CapturePaneVisible(name)
+	content, err := h.ctrl.CapturePaneVisible(agent.Target())
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
@@ -148,19 +324,91 @@ func (h *Handler) captureScreen(w http.ResponseWriter, _ *http.Request, name str
 	writeJSON(w, http.StatusOK, map[string]any{"screen": content})
 }
 
-// killAgent handles DELETE /api/agents/{name}.
-func (h *Handler) killAgent(w http.ResponseWriter, _ *http.Request, name string) {
+// killAgent handles DELETE /api/agents/{name}?graceful=&grace= — stop the
+// agent's processes, then its session.
+func (h *Handler) killAgent(w http.ResponseWriter, r *http.Request, name string) {
 	if _, ok := h.registry.GetAgent(name); !ok {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
 		return
 	}
 
-	if err := h.ctrl.KillSession(name); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+	opts := lifecycle.KillOptions{Graceful: r.URL.Query().Get("graceful") == "true"}
+	if v := r.URL.Query().Get("grace"); v != "" {
+		grace, err := time.ParseDuration(v)
+		if err != nil || grace <= 0 || grace > lifecycle.MaxKillGrace {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "grace must be a duration up to " + lifecycle.MaxKillGrace.String()})
+			return
+		}
+		opts.Grace = grace
+	}
+
+	report, err := h.life.Kill(r.Context(), name, opts)
+	h.recordAudit(r, "kill", name, map[string]any{"graceful": opts.Graceful, "exited": report.Exited, "sigterm": report.SIGTERM, "sigkill": report.SIGKILL}, err)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "sigterm": report.SIGTERM, "sigkill": report.SIGKILL})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "exited": report.Exited, "sigterm": report.SIGTERM, "sigkill": report.SIGKILL})
+}
+
+// restartAgent handles POST /api/agents/{name}/restart — respawn the pane
+// in place and wait for the new agent process.
+func (h *Handler) restartAgent(w http.ResponseWriter, r *http.Request, name string) {
+	if _, ok := h.registry.GetAgent(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+
+	res, err := h.life.Restart(r.Context(), name)
+	h.recordAudit(r, "restart", name, map[string]any{"prevPid": res.PrevPID, "pid": res.PID}, err)
+	if err != nil {
+		writeJSON(w, spawnStatus(err), map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"agent": res.Agent, "prevPid": res.PrevPID, "pid": res.PID})
+}
+
+// spawnAgent handles POST /api/agents — create a session and wait for its
+// agent to come up.
+func (h *Handler) spawnAgent(w http.ResponseWriter, r *http.Request, identity auth.Identity) {
+	var spec lifecycle.Spec
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&spec); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid JSON body"})
+		return
+	}
+	name, err := spec.SessionName()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if !requireScope(w, identity.ScopeFor(auth.SessionRef(name)), auth.ScopeAdmin) {
+		h.auditDenied(r, "spawn", name, "requires admin scope")
+		return
+	}
+
+	agent, err := h.life.Spawn(r.Context(), spec)
+	h.recordAudit(r, "spawn", name, spec.AuditDetail(), err)
+	if err != nil {
+		writeJSON(w, spawnStatus(err), map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"agent": agent})
+}
+
+// spawnStatus maps a spawn or restart error to an HTTP status.
+func spawnStatus(err error) int {
+	switch {
+	case errors.Is(err, lifecycle.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, lifecycle.ErrInvalidSpec):
+		return http.StatusBadRequest
+	case errors.Is(err, lifecycle.ErrExists):
+		return http.StatusConflict
+	case errors.Is(err, lifecycle.ErrNotStarted):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload map[string]any) {
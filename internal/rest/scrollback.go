@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gastownhall/tmux-adapter/internal/ansi"
+)
+
+const (
+	defaultPageLimit = 1000
+	maxPageLimit     = 10000
+)
+
+// pageRequest holds the parsed paging parameters of a scrollback request.
+// Line indexes are absolute: 0 is the oldest scrollback line and total-1 is
+// the last visible line. end is exclusive.
+type pageRequest struct {
+	start, end int
+	hasEnd     bool
+	tail       int
+	limit      int
+}
+
+// captureHistory handles GET /api/agents/{name}/history.
+//
+// Query parameters: start, end (exclusive), tail, limit, cursor, and
+// format (ansi, plain or html).
+func (h *Handler) captureHistory(w http.ResponseWriter, r *http.Request, name string) {
+	agent, ok := h.registry.GetAgent(name)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+
+	params := r.URL.Query()
+	format := params.Get("format")
+	if format == "" {
+		format = "ansi"
+	}
+	if format != "ansi" && format != "plain" && format != "html" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid format: expected ansi, plain or html"})
+		return
+	}
+
+	req, err := parsePageRequest(params)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	historySize, height, err := h.ctrl.PaneLineCounts(agent.Target())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	total := historySize + height
+
+	start, end, next := resolvePage(req, total)
+	lines := []string{}
+	if end > start {
+		// Translate absolute indexes to tmux coordinates (0 = first visible line).
+		out, err := h.ctrl.CapturePaneRange(agent.Target(), start-historySize, end-1-historySize)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		lines = strings.Split(out, "\n")
+		if len(lines) > end-start {
+			lines = lines[:end-start]
+		}
+	}
+
+	switch format {
+	case "plain":
+		for i, line := range lines {
+			lines[i] = ansi.Strip(line)
+		}
+	case "html":
+		lines = ansi.LinesToHTML(lines)
+	}
+
+	resp := map[string]any{
+		"format": format,
+		"start":  start,
+		"end":    start + len(lines),
+		"total":  total,
+		"lines":  lines,
+	}
+	if next != "" {
+		resp["nextCursor"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parsePageRequest reads start/end/tail/limit/cursor query parameters.
+// A cursor replaces start and end with the values of the previous page.
+func parsePageRequest(params url.Values) (pageRequest, error) {
+	var req pageRequest
+	var err error
+
+	if req.limit, err = intParam(params.Get("limit"), defaultPageLimit, 1, maxPageLimit); err != nil {
+		return req, fmt.Errorf("invalid limit: %v", err)
+	}
+
+	if cursor := strings.TrimSpace(params.Get("cursor")); cursor != "" {
+		req.start, req.end, err = decodeCursor(cursor)
+		if err != nil {
+			return req, err
+		}
+		req.hasEnd = true
+		return req, nil
+	}
+
+	if req.tail, err = intParam(params.Get("tail"), 0, 0, maxInt); err != nil {
+		return req, fmt.Errorf("invalid tail: %v", err)
+	}
+	if req.start, err = intParam(params.Get("start"), 0, 0, maxInt); err != nil {
+		return req, fmt.Errorf("invalid start: %v", err)
+	}
+	if raw := params.Get("end"); raw != "" {
+		if req.end, err = intParam(raw, 0, 0, maxInt); err != nil {
+			return req, fmt.Errorf("invalid end: %v", err)
+		}
+		req.hasEnd = true
+	}
+	if req.tail > 0 && (params.Get("start") != "" || req.hasEnd) {
+		return req, fmt.Errorf("tail cannot be combined with start or end")
+	}
+	if req.hasEnd && req.end < req.start {
+		return req, fmt.Errorf("end must not be less than start")
+	}
+	return req, nil
+}
+
+// resolvePage clamps a page request to the available lines and returns the
+// line range to capture plus a cursor for the next page ("" on the last page).
+func resolvePage(req pageRequest, total int) (start, end int, next string) {
+	start, end = req.start, total
+	if req.tail > 0 {
+		start = max(0, total-req.tail)
+	}
+	if req.hasEnd {
+		end = min(req.end, total)
+	}
+	start = min(start, total)
+	end = max(start, end)
+
+	if end-start > req.limit {
+		next = encodeCursor(start+req.limit, end)
+		end = start + req.limit
+	}
+	return start, end, next
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+func encodeCursor(start, end int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", start, end)))
+}
+
+func decodeCursor(cursor string) (start, end int, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor")
+	}
+	startStr, endStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid cursor")
+	}
+	start, err1 := strconv.Atoi(startStr)
+	end, err2 := strconv.Atoi(endStr)
+	if err1 != nil || err2 != nil || start < 0 || end < start {
+		return 0, 0, fmt.Errorf("invalid cursor")
+	}
+	return start, end, nil
+}
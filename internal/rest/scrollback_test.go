@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestResolvePage(t *testing.T) {
+	cases := []struct {
+		name               string
+		query              string
+		total              int
+		wantStart, wantEnd int
+		wantNext           bool
+	}{
+		{name: "defaults_whole_buffer", query: "", total: 50, wantStart: 0, wantEnd: 50},
+		{name: "tail", query: "tail=10", total: 50, wantStart: 40, wantEnd: 50},
+		{name: "tail_larger_than_total", query: "tail=500", total: 50, wantStart: 0, wantEnd: 50},
+		{name: "range", query: "start=5&end=15", total: 50, wantStart: 5, wantEnd: 15},
+		{name: "end_clamped", query: "start=45&end=90", total: 50, wantStart: 45, wantEnd: 50},
+		{name: "start_past_total", query: "start=80", total: 50, wantStart: 50, wantEnd: 50},
+		{name: "limit_paginates", query: "start=0&limit=20", total: 50, wantStart: 0, wantEnd: 20, wantNext: true},
+		{name: "tail_with_limit", query: "tail=30&limit=10", total: 50, wantStart: 20, wantEnd: 30, wantNext: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, _ := url.ParseQuery(tc.query)
+			req, err := parsePageRequest(params)
+			if err != nil {
+				t.Fatalf("parsePageRequest(%q) error = %v", tc.query, err)
+			}
+			start, end, next := resolvePage(req, tc.total)
+			if start != tc.wantStart || end != tc.wantEnd {
+				t.Fatalf("range = [%d,%d), want [%d,%d)", start, end, tc.wantStart, tc.wantEnd)
+			}
+			if (next != "") != tc.wantNext {
+				t.Fatalf("next = %q, wantNext = %v", next, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestResolvePageCursorWalk(t *testing.T) {
+	params, _ := url.ParseQuery("start=10&end=35&limit=10")
+	req, err := parsePageRequest(params)
+	if err != nil {
+		t.Fatalf("parsePageRequest() error = %v", err)
+	}
+
+	var pages [][2]int
+	for {
+		start, end, next := resolvePage(req, 100)
+		pages = append(pages, [2]int{start, end})
+		if next == "" {
+			break
+		}
+		cursorParams := url.Values{"cursor": {next}, "limit": {"10"}}
+		if req, err = parsePageRequest(cursorParams); err != nil {
+			t.Fatalf("parsePageRequest(cursor) error = %v", err)
+		}
+	}
+
+	want := [][2]int{{10, 20}, {20, 30}, {30, 35}}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Fatalf("pages = %v, want %v", pages, want)
+		}
+	}
+}
+
+func TestParsePageRequestErrors(t *testing.T) {
+	for _, query := range []string{
+		"tail=5&start=1",
+		"start=10&end=5",
+		"limit=0",
+		"start=-1",
+		"cursor=not-a-cursor",
+	} {
+		params, _ := url.ParseQuery(query)
+		if _, err := parsePageRequest(params); err == nil {
+			t.Fatalf("parsePageRequest(%q) expected error", query)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/history"
+)
+
+const (
+	defaultSearchContext = 2
+	maxSearchContext     = 20
+	defaultSearchLimit   = 100
+	maxSearchLimit       = 1000
+)
+
+// handleSearch handles GET /api/search?q=&agent=&since=&context=&limit= —
+// full-text search across all recorded transcripts, including dead agents.
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authorize(w, r, auth.ScopeRead)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	if h.history == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "history recording is disabled"})
+		return
+	}
+
+	query, err := parseSearchQuery(r, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	if identity.Restricted() {
+		query.Visible = h.visibleByName(identity)
+	}
+
+	matches, err := h.history.Search(query)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if matches == nil {
+		matches = []history.Match{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"matches": matches})
+}
+
+// parseSearchQuery builds a history query from request parameters.
+// since accepts an RFC 3339 timestamp or a duration relative to now (e.g. "24h").
+func parseSearchQuery(r *http.Request, now time.Time) (history.Query, error) {
+	params := r.URL.Query()
+	q := history.Query{
+		Text:    params.Get("q"),
+		Agent:   strings.TrimSpace(params.Get("agent")),
+		Context: defaultSearchContext,
+		Limit:   defaultSearchLimit,
+	}
+	if q.Text == "" {
+		return q, fmt.Errorf("q parameter required")
+	}
+
+	var err error
+	if q.Since, err = timeParam(params.Get("since"), now); err != nil {
+		return q, fmt.Errorf("invalid since: %v", err)
+	}
+	if q.Context, err = intParam(params.Get("context"), defaultSearchContext, 0, maxSearchContext); err != nil {
+		return q, fmt.Errorf("invalid context: %v", err)
+	}
+	if q.Limit, err = intParam(params.Get("limit"), defaultSearchLimit, 1, maxSearchLimit); err != nil {
+		return q, fmt.Errorf("invalid limit: %v", err)
+	}
+	return q, nil
+}
+
+// timeParam parses an optional RFC 3339 timestamp or a duration back from now.
+func timeParam(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC 3339 timestamp or duration")
+}
+
+// intParam parses an optional integer query parameter within [lo, hi].
+func intParam(raw string, def, lo, hi int) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("not a number")
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("must be between %d and %d", lo, hi)
+	}
+	return n, nil
+}
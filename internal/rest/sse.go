@@ -0,0 +1,351 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+)
+
+const (
+	outputRingSize    = 512              // output chunks retained per agent for resume
+	eventRingSize     = 256              // lifecycle events retained for resume
+	outputLinger      = 30 * time.Second // keep pipe-pane alive after the last SSE client leaves
+	sseKeepalive      = 15 * time.Second
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// outputStreams owns one replayable output feed per agent for SSE clients.
+// Each feed holds a PipePaneManager subscription while clients are connected,
+// plus a linger period so a reconnecting client can resume without gaps.
+type outputStreams struct {
+	pipeMgr *tmux.PipePaneManager
+	epoch   string
+	mu      sync.Mutex
+	feeds   map[string]*outputFeed
+}
+
+type outputFeed struct {
+	*feed
+	pipeCh  <-chan []byte // nil while pipe-pane is not subscribed
+	clients int
+	linger  *time.Timer
+}
+
+func newOutputStreams(pipeMgr *tmux.PipePaneManager, epoch string) *outputStreams {
+	return &outputStreams{
+		pipeMgr: pipeMgr,
+		epoch:   epoch,
+		feeds:   make(map[string]*outputFeed),
+	}
+}
+
+// acquire registers an SSE client for an agent, activating pipe-pane if needed.
+func (o *outputStreams) acquire(agent string) (*outputFeed, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	of, ok := o.feeds[agent]
+	if !ok {
+		of = &outputFeed{feed: newFeed(o.epoch, outputRingSize)}
+		o.feeds[agent] = of
+	}
+	if of.linger != nil {
+		of.linger.Stop()
+		of.linger = nil
+	}
+	if of.pipeCh == nil {
+		ch, err := o.pipeMgr.Subscribe(agent)
+		if err != nil {
+			return nil, err
+		}
+		of.pipeCh = ch
+		go func(f *feed, ch <-chan []byte) {
+			for chunk := range ch {
+				f.publish("output", nil, chunk)
+			}
+		}(of.feed, ch)
+	}
+	of.clients++
+	return of, nil
+}
+
+// release unregisters an SSE client. When the last client leaves, pipe-pane is
+// kept alive for outputLinger before being released.
+func (o *outputStreams) release(agent string, of *outputFeed) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.feeds[agent] != of {
+		return // feed was closed while the client was connected
+	}
+	of.clients--
+	if of.clients > 0 {
+		return
+	}
+	of.linger = time.AfterFunc(outputLinger, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if of.clients == 0 && of.pipeCh != nil {
+			o.pipeMgr.Unsubscribe(agent, of.pipeCh)
+			of.pipeCh = nil
+		}
+	})
+}
+
+// closeAgent ends all output streams for an agent (e.g. when it is removed).
+func (o *outputStreams) closeAgent(agent string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if of, ok := o.feeds[agent]; ok {
+		o.closeFeed(agent, of)
+		delete(o.feeds, agent)
+	}
+}
+
+// closeAll ends every output stream.
+func (o *outputStreams) closeAll() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for agent, of := range o.feeds {
+		o.closeFeed(agent, of)
+		delete(o.feeds, agent)
+	}
+}
+
+func (o *outputStreams) closeFeed(agent string, of *outputFeed) {
+	if of.linger != nil {
+		of.linger.Stop()
+	}
+	if of.pipeCh != nil {
+		o.pipeMgr.Unsubscribe(agent, of.pipeCh)
+		of.pipeCh = nil
+	}
+	of.close()
+}
+
+// PublishAgentEvent fans a registry lifecycle event out to /api/events clients.
+// eventType is the registry event type ("added", "removed", "updated",
+// "restarted", "crashed") and data is the JSON event body also sent to
+// WebSocket clients.
+func (h *Handler) PublishAgentEvent(eventType string, agent agents.Agent, data []byte) {
+	ref := auth.RefOf(agent)
+	h.events.publish("agent-"+eventType, &ref, data)
+	if eventType == "removed" {
+		h.outputs.closeAgent(agent.Name)
+	}
+}
+
+// Close ends all open Server-Sent Event streams.
+func (h *Handler) Close() {
+	h.outputs.closeAll()
+	h.events.close()
+}
+
+// streamOutput handles GET /api/agents/{name}/output/stream — an SSE stream of
+// raw terminal output. encoding=base64 (default) sends base64 chunks;
+// encoding=text sends each chunk as a JSON string.
+func (h *Handler) streamOutput(w http.ResponseWriter, r *http.Request, name string) {
+	if _, ok := h.registry.GetAgent(name); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "agent not found"})
+		return
+	}
+
+	encoding := r.URL.Query().Get("encoding")
+	if encoding == "" {
+		encoding = "base64"
+	}
+	if encoding != "base64" && encoding != "text" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid encoding: expected base64 or text"})
+		return
+	}
+	encode := func(data []byte) string {
+		if encoding == "text" {
+			s, _ := json.Marshal(strings.ToValidUTF8(string(data), "�"))
+			return string(s)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	of, err := h.outputs.acquire(name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	defer h.outputs.release(name, of)
+
+	lastID := lastEventID(r)
+	ch, backlog, gap := of.subscribe(lastID)
+	defer of.unsubscribe(ch)
+
+	sw, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	if lastID == "" {
+		// Fresh connection: start from the current screen contents.
+		screen, err := h.ctrl.CapturePaneVisible(h.registry.Target(name))
+		if err != nil {
+			log.Printf("sse output %s: snapshot: %v", name, err)
+		} else {
+			sw.send("", "snapshot", encode([]byte(screen)))
+		}
+	} else if gap {
+		sw.send("", "gap", `{"resumed":false}`)
+	}
+	render := func(e feedEntry) (string, bool) { return encode(e.data), true }
+	for _, e := range backlog {
+		sw.sendEntry(of.feed, e, render)
+	}
+	if !sw.flush() {
+		return
+	}
+
+	sw.run(r, ch, render, of.feed)
+	if of.isClosed() {
+		// The agent went away; tell the client not to reconnect.
+		sw.send("", "end", `{}`)
+		sw.flush()
+	}
+}
+
+// streamEvents handles GET /api/events — an SSE stream of agent lifecycle
+// events (agent-added, agent-removed, agent-updated, agent-restarted,
+// agent-crashed).
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authorize(w, r, auth.ScopeRead)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+
+	lastID := lastEventID(r)
+	ch, backlog, gap := h.events.subscribe(lastID)
+	defer h.events.unsubscribe(ch)
+
+	sw, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	if lastID == "" || gap {
+		// Fresh connection (or unrecoverable gap): send the full agent list.
+		snapshot, err := json.Marshal(map[string]any{"agents": auth.VisibleAgents(identity, h.registry.GetAgents())})
+		if err != nil {
+			log.Printf("sse events: marshal snapshot: %v", err)
+			return
+		}
+		sw.send("", "snapshot", string(snapshot))
+	}
+	render := func(e feedEntry) (string, bool) {
+		return string(e.data), e.agent == nil || identity.CanSee(*e.agent)
+	}
+	if !gap {
+		for _, e := range backlog {
+			sw.sendEntry(h.events, e, render)
+		}
+	}
+	if !sw.flush() {
+		return
+	}
+
+	sw.run(r, ch, render, h.events)
+}
+
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get(lastEventIDHeader); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// sseWriter writes Server-Sent Events and tracks the first write error.
+type sseWriter struct {
+	w   io.Writer
+	rc  *http.ResponseController
+	err error
+}
+
+func startSSE(w http.ResponseWriter) (*sseWriter, bool) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sw := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	return sw, sw.flush()
+}
+
+func (sw *sseWriter) send(id, event, data string) {
+	if sw.err != nil {
+		return
+	}
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteByte('\n')
+	_, sw.err = io.WriteString(sw.w, b.String())
+}
+
+func (sw *sseWriter) flush() bool {
+	if sw.err == nil {
+		sw.err = sw.rc.Flush()
+	}
+	if sw.err != nil {
+		log.Printf("sse write: %v", sw.err)
+		return false
+	}
+	return true
+}
+
+// sendEntry sends a feed entry rendered by render, unless render filters it out.
+func (sw *sseWriter) sendEntry(f *feed, e feedEntry, render func(feedEntry) (string, bool)) {
+	if data, ok := render(e); ok {
+		sw.send(f.formatID(e.id), e.event, data)
+	}
+}
+
+// run forwards feed entries until the client disconnects or the feed closes
+// the subscription, sending periodic keepalive comments.
+func (sw *sseWriter) run(r *http.Request, ch <-chan feedEntry, render func(feedEntry) (string, bool), f *feed) {
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			sw.sendEntry(f, e, render)
+			if !sw.flush() {
+				return
+			}
+		case <-keepalive.C:
+			if _, sw.err = io.WriteString(sw.w, ": keepalive\n\n"); sw.err != nil || !sw.flush() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// getRestartPolicy handles GET /api/agents/{name}/restart-policy — the
+// agent's restart policy, state and restart counts. It also answers for
+// agents that are gone, e.g. after a crash loop.
+func (h *Handler) getRestartPolicy(w http.ResponseWriter, _ *http.Request, name string) {
+	writeJSON(w, http.StatusOK, map[string]any{"supervisor": h.life.Supervision(name)})
+}
+
+// setRestartPolicy handles PUT /api/agents/{name}/restart-policy — override
+// the agent's policy. Fields left out keep their current values.
+func (h *Handler) setRestartPolicy(w http.ResponseWriter, r *http.Request, name string) {
+	policy := h.life.RestartPolicy(name)
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&policy); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid restart policy: " + err.Error()})
+		return
+	}
+	err := h.life.SetRestartPolicy(name, policy)
+	h.recordAudit(r, "restart-policy", name, map[string]any{"policy": policy}, err)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "supervisor": h.life.Supervision(name)})
+}
+
+// clearRestartPolicy handles DELETE /api/agents/{name}/restart-policy —
+// fall back to the role's policy or the default.
+func (h *Handler) clearRestartPolicy(w http.ResponseWriter, r *http.Request, name string) {
+	h.life.ClearRestartPolicy(name)
+	h.recordAudit(r, "restart-policy", name, map[string]any{"policy": nil}, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "supervisor": h.life.Supervision(name)})
+}
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
+)
+
+// Agents in a restart backoff or crash loop are not in the registry; a token
+// restricted to one rig must still not reach another rig's policies.
+func TestRestartPolicyOfAbsentAgentHonorsACL(t *testing.T) {
+	authn, err := auth.NewAuthenticator("", []auth.Token{
+		{Name: "alpha-admin", Token: "tok-a", Scope: auth.ScopeAdmin, Rules: []auth.Rule{{Rigs: []string{"alpha"}}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := agents.NewRegistry(nil, "", agents.DiscoveryRules{})
+	life := lifecycle.New(nil, registry, "", lifecycle.DefaultRestartPolicies())
+	mux := http.NewServeMux()
+	New(Options{Registry: registry, Life: life, Authn: authn}).Register(mux)
+
+	do := func(method, agent, body string) int {
+		req := httptest.NewRequest(method, "/api/agents/"+agent+"/restart-policy", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok-a")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	const other = "gt-beta-crew-bob"
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if code := do(method, other, `{"mode":"always"}`); code != http.StatusNotFound {
+			t.Errorf("%s restart-policy of another rig's agent = %d, want 404", method, code)
+		}
+	}
+	if mode := life.RestartPolicy(other).Mode; mode == lifecycle.RestartAlways {
+		t.Fatal("restricted token changed another rig's restart policy")
+	}
+
+	const own = "gt-alpha-crew-bob"
+	if code := do(http.MethodPut, own, `{"mode":"always"}`); code != http.StatusOK {
+		t.Fatalf("PUT restart-policy of own rig's agent = %d, want 200", code)
+	}
+	if mode := life.RestartPolicy(own).Mode; mode != lifecycle.RestartAlways {
+		t.Fatalf("own agent's mode = %q, want always", mode)
+	}
+}
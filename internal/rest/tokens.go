@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+const (
+	defaultTokenTTL = 15 * time.Minute
+	maxTokenTTL     = 24 * time.Hour
+)
+
+// mintRequest is the body of POST /api/tokens.
+type mintRequest struct {
+	Name   string      `json:"name"`
+	Scope  auth.Scope  `json:"scope"`  // default read
+	TTL    string      `json:"ttl"`    // Go duration, default 15m
+	Agents []string    `json:"agents"` // shorthand for a single rule matching these agent names
+	Rules  []auth.Rule `json:"rules"`
+}
+
+// mintToken handles POST /api/tokens — mint a short-lived signed token, e.g.
+// for a dashboard backend to hand to a browser. The minted scope cannot exceed
+// the caller's, and a caller restricted by ACL rules passes them on. Signed
+// tokens cannot mint tokens, so a minted token cannot outlive its issuer's.
+func (h *Handler) mintToken(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.authorize(w, r, auth.ScopeAdmin)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	if identity.Issuer != "" {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "forbidden: signed tokens cannot mint tokens"})
+		return
+	}
+	signer := h.authn.Signer()
+	if signer == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "signed tokens are disabled (start with --token-signing-key-file)"})
+		return
+	}
+
+	var req mintRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	claims, ttl, err := buildClaims(req, identity)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if !identity.Scope.Allows(claims.Scope) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "forbidden: cannot mint a token above your own scope"})
+		return
+	}
+
+	now := time.Now()
+	token, err := signer.Mint(claims, now, ttl)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token":     token,
+		"name":      claims.Subject,
+		"scope":     claims.Scope,
+		"expiresAt": now.Add(ttl).UTC().Format(time.RFC3339),
+	})
+}
+
+// buildClaims validates a mint request on behalf of issuer. The subject is
+// namespaced by the issuer ("issuer/name"), so a minted token cannot take on
+// the name of another identity in the audit log or rate limits.
+func buildClaims(req mintRequest, issuer auth.Identity) (auth.Claims, time.Duration, error) {
+	if req.Name == "" {
+		return auth.Claims{}, 0, fmt.Errorf("name required")
+	}
+	scope := req.Scope
+	if scope == auth.ScopeNone {
+		scope = auth.ScopeRead
+	}
+
+	ttl := defaultTokenTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			return auth.Claims{}, 0, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = d
+	}
+	if ttl <= 0 || ttl > maxTokenTTL {
+		return auth.Claims{}, 0, fmt.Errorf("ttl must be between 0 and %s", maxTokenTTL)
+	}
+
+	rules := req.Rules
+	if len(req.Agents) > 0 {
+		rules = append(rules, auth.Rule{Agents: req.Agents})
+	}
+	if issuer.Restricted() {
+		if len(rules) > 0 {
+			return auth.Claims{}, 0, fmt.Errorf("a token with access rules cannot mint tokens with different rules")
+		}
+		rules = issuer.Rules
+	}
+
+	return auth.Claims{Subject: issuer.Name + "/" + req.Name, Scope: scope, Rules: rules, Issuer: issuer.Name}, ttl, nil
+}
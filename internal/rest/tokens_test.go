@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+func TestBuildClaimsDefaults(t *testing.T) {
+	admin := auth.Identity{Name: "backend", Scope: auth.ScopeAdmin}
+	c, ttl, err := buildClaims(mintRequest{Name: "browser-1", Agents: []string{"gt-myrig-*"}}, admin)
+	if err != nil {
+		t.Fatalf("buildClaims() error = %v", err)
+	}
+	if c.Subject != "backend/browser-1" || c.Scope != auth.ScopeRead || ttl != defaultTokenTTL || c.Issuer != "backend" {
+		t.Fatalf("claims = %+v, ttl = %v", c, ttl)
+	}
+	if len(c.Rules) != 1 || c.Rules[0].Agents[0] != "gt-myrig-*" {
+		t.Fatalf("rules = %+v, want agents shorthand rule", c.Rules)
+	}
+}
+
+func TestBuildClaimsInheritsIssuerRules(t *testing.T) {
+	restricted := auth.Identity{Name: "rig-admin", Scope: auth.ScopeAdmin, Rules: []auth.Rule{{Rigs: []string{"myrig"}}}}
+
+	c, _, err := buildClaims(mintRequest{Name: "browser-1", Scope: auth.ScopeOperate}, restricted)
+	if err != nil {
+		t.Fatalf("buildClaims() error = %v", err)
+	}
+	if len(c.Rules) != 1 || c.Rules[0].Rigs[0] != "myrig" {
+		t.Fatalf("rules = %+v, want issuer rules", c.Rules)
+	}
+
+	if _, _, err := buildClaims(mintRequest{Name: "x", Agents: []string{"*"}}, restricted); err == nil {
+		t.Fatal("expected error when a restricted issuer requests different rules")
+	}
+}
+
+func TestBuildClaimsRejectsBadInput(t *testing.T) {
+	admin := auth.Identity{Name: "backend", Scope: auth.ScopeAdmin}
+	tests := map[string]mintRequest{
+		"missing name": {TTL: "1m"},
+		"bad ttl":      {Name: "x", TTL: "soon"},
+		"ttl too long": {Name: "x", TTL: (maxTokenTTL + time.Minute).String()},
+		"negative ttl": {Name: "x", TTL: "-1m"},
+	}
+	for name, req := range tests {
+		if _, _, err := buildClaims(req, admin); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -16,9 +17,19 @@ type SessionInfo struct {
 	Attached bool
 }
 
+// CursorInfo holds a pane's dimensions and cursor state.
+type CursorInfo struct {
+	Width   int
+	Height  int
+	X       int
+	Y       int
+	Visible bool
+}
+
 // PaneInfo holds tmux pane details.
 type PaneInfo struct {
 	PaneID  string
+	Window  int // window index
 	Command string
 	PID     string
 	WorkDir string
@@ -65,28 +76,102 @@ func (cm *ControlMode) ShowEnvironment(session, key string) (string, error) {
 	return "", nil
 }
 
-// GetPaneInfo returns pane details for the first pane in a session.
-func (cm *ControlMode) GetPaneInfo(session string) (PaneInfo, error) {
-	out, err := cm.Execute(fmt.Sprintf("list-panes -t '%s' -F '#{pane_id}\t#{pane_current_command}\t#{pane_pid}\t#{pane_current_path}'", session))
+// ShowEnvironmentAll returns a session's environment. Variables marked for
+// removal from new processes (shown as -KEY) are omitted.
+func (cm *ControlMode) ShowEnvironmentAll(session string) (map[string]string, error) {
+	out, err := cm.Execute(fmt.Sprintf("show-environment -t '%s'", session))
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvironment(out), nil
+}
+
+func parseEnvironment(out string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if key, val, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(key, "-") {
+			env[key] = val
+		}
+	}
+	return env
+}
+
+// SetEnvironment sets a session environment variable. It applies to
+// processes started in the session afterwards, not to running ones.
+func (cm *ControlMode) SetEnvironment(session, key, value string) error {
+	_, err := cm.Execute(fmt.Sprintf("set-environment -t '%s' %s %s", session, key, shellQuote(value)))
+	return err
+}
+
+// UnsetEnvironment removes a session environment variable.
+func (cm *ControlMode) UnsetEnvironment(session, key string) error {
+	_, err := cm.Execute(fmt.Sprintf("set-environment -u -t '%s' %s", session, key))
+	return err
+}
+
+// paneFormat is the list-panes/display-message format parsed by parsePane.
+const paneFormat = "#{pane_id}\t#{window_index}\t#{pane_current_command}\t#{pane_pid}\t#{pane_current_path}"
+
+// GetPaneInfo returns pane details for a pane ID, or for a session's active
+// pane.
+func (cm *ControlMode) GetPaneInfo(target string) (PaneInfo, error) {
+	out, err := cm.Execute(fmt.Sprintf("display-message -p -t '%s' '%s'", target, paneFormat))
 	if err != nil {
 		return PaneInfo{}, err
 	}
+	return parsePane(strings.TrimSpace(out))
+}
 
-	// Take the first pane
-	line := strings.SplitN(strings.TrimSpace(out), "\n", 2)[0]
-	parts := strings.SplitN(line, "\t", 4)
-	if len(parts) < 4 {
-		return PaneInfo{}, fmt.Errorf("unexpected pane info format: %q", line)
+// ListPanes returns every pane in every window of a session, in window and
+// pane order.
+func (cm *ControlMode) ListPanes(session string) ([]PaneInfo, error) {
+	out, err := cm.Execute(fmt.Sprintf("list-panes -s -t '%s' -F '%s'", session, paneFormat))
+	if err != nil {
+		return nil, err
 	}
+	var panes []PaneInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		pane, err := parsePane(line)
+		if err != nil {
+			return nil, err
+		}
+		panes = append(panes, pane)
+	}
+	return panes, nil
+}
 
+func parsePane(line string) (PaneInfo, error) {
+	parts := strings.SplitN(line, "\t", 5)
+	if len(parts) < 5 {
+		return PaneInfo{}, fmt.Errorf("unexpected pane info format: %q", line)
+	}
+	window, _ := strconv.Atoi(parts[1])
 	return PaneInfo{
 		PaneID:  parts[0],
-		Command: parts[1],
-		PID:     parts[2],
-		WorkDir: parts[3],
+		Window:  window,
+		Command: parts[2],
+		PID:     parts[3],
+		WorkDir: parts[4],
 	}, nil
 }
 
+// PanePIDs returns the PIDs of every pane's process in a session.
+func (cm *ControlMode) PanePIDs(session string) ([]int, error) {
+	out, err := cm.Execute(fmt.Sprintf("list-panes -s -t '%s' -F '#{pane_pid}'", session))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, line := range strings.Fields(out) {
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected pane pid %q", line)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
 // SendKeysLiteral sends text in literal mode (no key name interpretation).
 func (cm *ControlMode) SendKeysLiteral(target, text string) error {
 	_, err := cm.Execute(fmt.Sprintf("send-keys -t '%s' -l %s", target, shellQuote(text)))
@@ -219,6 +304,59 @@ func (cm *ControlMode) CapturePaneHistory(session string) (string, error) {
 	return out, nil
 }
 
+// CapturePaneRange captures lines start through end (inclusive) with ANSI escape codes.
+// Line numbers follow tmux conventions: 0 is the first visible line and negative
+// numbers index into the scrollback history.
+func (cm *ControlMode) CapturePaneRange(target string, start, end int) (string, error) {
+	return cm.Execute(fmt.Sprintf("capture-pane -p -e -t '%s' -S %d -E %d", target, start, end))
+}
+
+// PaneLineCounts returns the number of scrollback history lines and the visible
+// pane height for a target.
+func (cm *ControlMode) PaneLineCounts(target string) (historySize, height int, err error) {
+	out, err := cm.DisplayMessage(target, "#{history_size}:#{pane_height}")
+	if err != nil {
+		return 0, 0, err
+	}
+	histStr, heightStr, ok := strings.Cut(out, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("unexpected line count format: %q", out)
+	}
+	historySize, err1 := strconv.Atoi(histStr)
+	height, err2 := strconv.Atoi(heightStr)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("unexpected line count format: %q", out)
+	}
+	return historySize, height, nil
+}
+
+// GetCursorInfo returns the pane size and cursor position of a target.
+func (cm *ControlMode) GetCursorInfo(target string) (CursorInfo, error) {
+	out, err := cm.DisplayMessage(target, "#{pane_width}:#{pane_height}:#{cursor_x}:#{cursor_y}:#{cursor_flag}")
+	if err != nil {
+		return CursorInfo{}, err
+	}
+	parts := strings.Split(out, ":")
+	if len(parts) != 5 {
+		return CursorInfo{}, fmt.Errorf("unexpected cursor info format: %q", out)
+	}
+	nums := make([]int, 5)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return CursorInfo{}, fmt.Errorf("unexpected cursor info format: %q", out)
+		}
+		nums[i] = n
+	}
+	return CursorInfo{
+		Width:   nums[0],
+		Height:  nums[1],
+		X:       nums[2],
+		Y:       nums[3],
+		Visible: nums[4] != 0,
+	}, nil
+}
+
 // ForceRedraw triggers a SIGWINCH by briefly changing the window size.
 // Uses resize-window (not resize-pane) because single-pane windows
 // constrain the pane to the window size, making resize-pane a no-op.
@@ -298,14 +436,14 @@ func (cm *ControlMode) DisplayMessage(session, format string) (string, error) {
 }
 
 // PipePaneStart activates pipe-pane for output-only streaming to a command.
-func (cm *ControlMode) PipePaneStart(session, command string) error {
-	_, err := cm.Execute(fmt.Sprintf("pipe-pane -o -t '%s' '%s'", session, command))
+func (cm *ControlMode) PipePaneStart(target, command string) error {
+	_, err := cm.Execute(fmt.Sprintf("pipe-pane -o -t '%s' '%s'", target, command))
 	return err
 }
 
-// PipePaneStop deactivates pipe-pane for a session.
-func (cm *ControlMode) PipePaneStop(session string) error {
-	_, err := cm.Execute(fmt.Sprintf("pipe-pane -t '%s'", session))
+// PipePaneStop deactivates pipe-pane for a pane.
+func (cm *ControlMode) PipePaneStop(target string) error {
+	_, err := cm.Execute(fmt.Sprintf("pipe-pane -t '%s'", target))
 	return err
 }
 
@@ -315,12 +453,57 @@ func (cm *ControlMode) ResizePaneTo(target string, cols, rows int) error {
 	return cm.ResizeWindow(target, cols, rows)
 }
 
+// WindowSize returns a session's current window size.
+func (cm *ControlMode) WindowSize(target string) (cols, rows int, err error) {
+	out, err := cm.DisplayMessage(target, "#{window_width}:#{window_height}")
+	if err != nil {
+		return 0, 0, err
+	}
+	colStr, rowStr, ok := strings.Cut(out, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("unexpected window size format: %q", out)
+	}
+	cols, err1 := strconv.Atoi(colStr)
+	rows, err2 := strconv.Atoi(rowStr)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("unexpected window size format: %q", out)
+	}
+	return cols, rows, nil
+}
+
 // ResizeWindow sets a session's window to an exact size.
 func (cm *ControlMode) ResizeWindow(target string, cols, rows int) error {
 	_, err := cm.Execute(fmt.Sprintf("resize-window -t '%s' -x %d -y %d", target, cols, rows))
 	return err
 }
 
+// NewSession starts a detached session running command in workDir with the
+// given session environment.
+func (cm *ControlMode) NewSession(name, workDir string, env map[string]string, command string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "new-session -d -s '%s' -c %s", name, shellQuote(workDir))
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " -e %s", shellQuote(k+"="+env[k]))
+	}
+	if command != "" {
+		b.WriteString(" " + shellQuote(command))
+	}
+	_, err := cm.Execute(b.String())
+	return err
+}
+
+// RespawnPane kills a session's pane process and reruns the command the pane
+// was created with, keeping the session and its environment.
+func (cm *ControlMode) RespawnPane(target string) error {
+	_, err := cm.Execute(fmt.Sprintf("respawn-pane -k -t '%s'", target))
+	return err
+}
+
 // KillSession destroys a tmux session.
 func (cm *ControlMode) KillSession(session string) error {
 	_, err := cm.Execute(fmt.Sprintf("kill-session -t '%s'", session))
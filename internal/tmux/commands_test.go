@@ -49,3 +49,27 @@ func TestCapturePaneVisibleFallsBackWhenNoAlternateScreen(t *testing.T) {
 		t.Fatalf("second command = %q, expected non-alternate fallback", executed[1])
 	}
 }
+
+func TestParseEnvironment(t *testing.T) {
+	env := parseEnvironment("GT_ROLE=crew\nFLAGS=a=b,c\n-REMOVED\nEMPTY=\n")
+	if len(env) != 3 || env["GT_ROLE"] != "crew" || env["FLAGS"] != "a=b,c" || env["EMPTY"] != "" {
+		t.Fatalf("parseEnvironment() = %v", env)
+	}
+	if _, ok := env["-REMOVED"]; ok {
+		t.Fatalf("parseEnvironment() kept a removal marker: %v", env)
+	}
+}
+
+func TestParsePane(t *testing.T) {
+	pane, err := parsePane("%12\t2\tclaude\t4242\t/tmp/gt/my rig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PaneInfo{PaneID: "%12", Window: 2, Command: "claude", PID: "4242", WorkDir: "/tmp/gt/my rig"}
+	if pane != want {
+		t.Fatalf("parsePane() = %+v, want %+v", pane, want)
+	}
+	if _, err := parsePane("%12\tclaude"); err == nil {
+		t.Fatal("parsePane() accepted a short line")
+	}
+}
@@ -219,11 +219,21 @@ func (cm *ControlMode) readLoop(stdout io.Reader) {
 		case strings.HasPrefix(line, "%session-changed"):
 			cm.notifications <- Notification{Type: "session-changed", Args: strings.TrimPrefix(line, "%session-changed ")}
 
+		case strings.HasPrefix(line, "%subscription-changed "):
+			cm.notifications <- Notification{Type: "subscription-changed", Args: strings.TrimPrefix(line, "%subscription-changed ")}
+
 		case strings.HasPrefix(line, "%output"):
 			cm.notifications <- Notification{Type: "output", Args: strings.TrimPrefix(line, "%output ")}
 
-		case strings.HasPrefix(line, "%window-"):
-			// Ignore window events
+		case strings.HasPrefix(line, "%window-"), strings.HasPrefix(line, "%unlinked-window-"), strings.HasPrefix(line, "%pane-mode-changed"):
+			// Windows coming, going or renamed as their command changes.
+			// These only hint at a rescan, so they are dropped rather than
+			// holding up command responses when the consumer is behind.
+			typ, args, _ := strings.Cut(strings.TrimPrefix(line, "%"), " ")
+			select {
+			case cm.notifications <- Notification{Type: typ, Args: args}:
+			default:
+			}
 
 		case strings.HasPrefix(line, "%layout-change"):
 			// Ignore layout changes
@@ -71,3 +71,51 @@ func TestExecuteReturnsResponse(t *testing.T) {
 		t.Fatalf("output = %q, want %q", out, "ok")
 	}
 }
+
+func TestReadLoopForwardsWindowNotifications(t *testing.T) {
+	cm := &ControlMode{notifications: make(chan Notification, 10)}
+	cm.readLoop(strings.NewReader(strings.Join([]string{
+		"%window-add @3",
+		"%layout-change @3 b25d,80x24,0,0,3 b25d,80x24,0,0,3 *",
+		"%unlinked-window-renamed @4 bash",
+		"%pane-mode-changed %5",
+		"%sessions-changed",
+	}, "\n")))
+	close(cm.notifications)
+
+	var got []Notification
+	for n := range cm.notifications {
+		got = append(got, n)
+	}
+	want := []Notification{
+		{Type: "window-add", Args: "@3"},
+		{Type: "unlinked-window-renamed", Args: "@4 bash"},
+		{Type: "pane-mode-changed", Args: "%5"},
+		{Type: "sessions-changed"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("notification %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadLoopDropsWindowNotificationsWhenBehind(t *testing.T) {
+	cm := &ControlMode{notifications: make(chan Notification, 1)}
+	done := make(chan struct{})
+	go func() {
+		cm.readLoop(strings.NewReader("%window-close @1\n%window-close @2\n%unlinked-window-close @3\n"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("readLoop blocked on window notifications nobody is reading")
+	}
+	if n := <-cm.notifications; n.Args != "@1" {
+		t.Fatalf("kept %+v, want the first window-close", n)
+	}
+}
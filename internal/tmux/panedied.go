@@ -0,0 +1,176 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaneDiedSubscription names the control-mode subscription whose value
+// changes when a watched pane dies. It arrives as a "subscription-changed"
+// notification whose Args start with this name.
+const PaneDiedSubscription = "pane-died"
+
+// paneDiedOption is the session option the pane-died hook sets. tmux 3.3 has
+// no notification a hook can raise directly, so the adapter subscribes to
+// this option across all sessions instead.
+const paneDiedOption = "@tmux-adapter-pane-died"
+
+// remainOnExitOption marks windows whose remain-on-exit the adapter turned on.
+// Gastown keeps some agents' dead panes itself (its auto-respawn hook); the
+// adapter leaves those panes, and their option, alone.
+const remainOnExitOption = "@tmux-adapter-remain-on-exit"
+
+// DeadPane is a pane whose process exited and that remain-on-exit kept open.
+type DeadPane struct {
+	Session string
+	PaneID  string
+	PID     int
+	Status  int // exit status; -1 when killed by a signal
+	Signal  int // terminating signal; 0 on a normal exit
+	Time    time.Time
+	Owned   bool // remain-on-exit was turned on by the adapter, not by gastown
+}
+
+// WatchPaneDied keeps the panes of the agent pane's window open when their
+// process exits and adds a pane-died hook that notifies the adapter, which
+// then reads the exit status and output and respawns or closes the pane (see
+// KillPane). remain-on-exit is set on the window, not the pane: tmux 3.3
+// does not run pane-died hooks for panes that only have the pane option.
+// Hooks gastown installs on the session are kept.
+func (cm *ControlMode) WatchPaneDied(session, pane string) error {
+	remain, err := cm.Execute(fmt.Sprintf("show-options -w -v -t '%s' remain-on-exit", pane))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(remain) != "on" {
+		if _, err := cm.Execute(fmt.Sprintf("set-option -w -t '%s' remain-on-exit on", pane)); err != nil {
+			return err
+		}
+		if _, err := cm.Execute(fmt.Sprintf("set-option -w -t '%s' %s 1", pane, remainOnExitOption)); err != nil {
+			return err
+		}
+	}
+
+	hooks, err := cm.Execute(fmt.Sprintf("show-hooks -t '%s' pane-died", session))
+	if err != nil {
+		return err
+	}
+	if len(adapterHooks(hooks)) > 0 {
+		return nil // left by an adapter that exited without cleanup
+	}
+	_, err = cm.Execute(fmt.Sprintf(`set-hook -a -t '%s' pane-died "set-option -F %s '#{pane_id} #{pane_pid}'"`, session, paneDiedOption))
+	return err
+}
+
+// UnwatchPaneDied removes the hook added by WatchPaneDied, and turns
+// remain-on-exit off again if WatchPaneDied turned it on, so the agent's
+// window closes panes when their process exits.
+func (cm *ControlMode) UnwatchPaneDied(session, pane string) error {
+	hooks, err := cm.Execute(fmt.Sprintf("show-hooks -t '%s' pane-died", session))
+	if err != nil {
+		return err
+	}
+	for _, hook := range adapterHooks(hooks) {
+		if _, err := cm.Execute(fmt.Sprintf("set-hook -u -t '%s' '%s'", session, hook)); err != nil {
+			return err
+		}
+	}
+
+	owned, err := cm.Execute(fmt.Sprintf("show-options -w -v -q -t '%s' %s", pane, remainOnExitOption))
+	if err != nil || strings.TrimSpace(owned) != "1" {
+		return err
+	}
+	if _, err := cm.Execute(fmt.Sprintf("set-option -w -u -t '%s' remain-on-exit", pane)); err != nil {
+		return err
+	}
+	_, err = cm.Execute(fmt.Sprintf("set-option -w -u -t '%s' %s", pane, remainOnExitOption))
+	return err
+}
+
+// adapterHooks returns the array entries (e.g. "pane-died[1]") of the
+// adapter's hooks in show-hooks output.
+func adapterHooks(out string) []string {
+	var hooks []string
+	for _, line := range strings.Split(out, "\n") {
+		entry, command, ok := strings.Cut(line, " ")
+		if ok && strings.HasPrefix(entry, "pane-died[") && strings.Contains(command, paneDiedOption) {
+			hooks = append(hooks, entry)
+		}
+	}
+	return hooks
+}
+
+// SubscribePaneDied subscribes this control client to pane-died hook
+// activity in every session (see PaneDiedSubscription).
+func (cm *ControlMode) SubscribePaneDied() error {
+	_, err := cm.Execute(fmt.Sprintf("refresh-client -B '%s::#{S:#{%s}}'", PaneDiedSubscription, paneDiedOption))
+	return err
+}
+
+// DeadPanes lists the dead panes of sessions whose pane-died hook has fired.
+func (cm *ControlMode) DeadPanes() ([]DeadPane, error) {
+	out, err := cm.Execute(fmt.Sprintf("list-panes -a -F '#{pane_dead}\t#{%s}\t#{%s}\t#{session_name}\t#{pane_id}\t#{pane_pid}\t#{pane_dead_status}\t#{pane_dead_signal}\t#{pane_dead_time}'", paneDiedOption, remainOnExitOption))
+	if err != nil {
+		return nil, err
+	}
+	return parseDeadPanes(out), nil
+}
+
+func parseDeadPanes(out string) []DeadPane {
+	var panes []DeadPane
+	// Not trimmed: a pane whose exit status tmux has not collected yet ends
+	// in empty fields.
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 9 || parts[0] != "1" || parts[1] == "" {
+			continue
+		}
+		pane := DeadPane{Owned: parts[2] == "1", Session: parts[3], PaneID: parts[4], Status: -1}
+		pane.PID, _ = strconv.Atoi(parts[5])
+		if status, err := strconv.Atoi(parts[6]); err == nil {
+			pane.Status = status
+		}
+		pane.Signal, _ = strconv.Atoi(parts[7])
+		if sec, err := strconv.ParseInt(parts[8], 10, 64); err == nil {
+			pane.Time = time.Unix(sec, 0)
+		}
+		panes = append(panes, pane)
+	}
+	return panes
+}
+
+// KillPane closes a pane, and its session if it was the last one.
+func (cm *ControlMode) KillPane(target string) error {
+	_, err := cm.Execute(fmt.Sprintf("kill-pane -t '%s'", target))
+	return err
+}
+
+// CapturePaneTail returns up to n of the last non-blank lines of a pane's
+// text, without escape codes or the "Pane is dead" line remain-on-exit adds.
+func (cm *ControlMode) CapturePaneTail(target string, n int) ([]string, error) {
+	out, err := cm.Execute(fmt.Sprintf("capture-pane -p -J -t '%s' -S -", target))
+	if err != nil {
+		return nil, err
+	}
+	return tailLines(out, n), nil
+}
+
+func tailLines(out string, n int) []string {
+	lines := strings.Split(out, "\n")
+	for len(lines) > 0 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last != "" && !strings.HasPrefix(last, "Pane is dead") {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return lines
+}
@@ -0,0 +1,48 @@
+package tmux
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDeadPanes(t *testing.T) {
+	out := "0\t%5 1234\t1\tgt-myrig-crew-bob\t%5\t1234\t\t\t\n" + // alive
+		"1\t\t\tother\t%6\t1300\t1\t\t1792334236\n" + // dead, not watched
+		"1\t%7 1400\t1\tgt-myrig-crew-bob\t%7\t1400\t3\t\t1792334236\n" +
+		"1\t%8 1500\t\tgt-myrig-deacon\t%8\t1500\t\t9\t1792334237\n" + // kept by gastown
+		"1\t%9 1600\t1\tgt-myrig-refinery\t%9\t1600\t\t\t" // status not collected yet
+	panes := parseDeadPanes(out)
+	if len(panes) != 3 {
+		t.Fatalf("parseDeadPanes() = %+v, want the three watched dead panes", panes)
+	}
+	if p := panes[0]; p.Session != "gt-myrig-crew-bob" || p.PaneID != "%7" || p.PID != 1400 || p.Status != 3 || p.Signal != 0 || p.Time.Unix() != 1792334236 || !p.Owned {
+		t.Errorf("exited pane = %+v", p)
+	}
+	if p := panes[1]; p.Status != -1 || p.Signal != 9 || p.Owned {
+		t.Errorf("signalled pane = %+v, want status -1, signal 9, not owned", p)
+	}
+	if p := panes[2]; p.PaneID != "%9" || p.Status != -1 || !p.Time.IsZero() {
+		t.Errorf("pane without status = %+v", p)
+	}
+}
+
+func TestAdapterHooks(t *testing.T) {
+	out := "pane-died[0] run-shell \"gt log crash --agent 'deacon' --exit-code #{pane_dead_status}\"\n" +
+		"pane-died[3] set-option -F @tmux-adapter-pane-died \"#{pane_id} #{pane_pid}\""
+	if got := adapterHooks(out); !slices.Equal(got, []string{"pane-died[3]"}) {
+		t.Fatalf("adapterHooks() = %q", got)
+	}
+	if got := adapterHooks("pane-died"); len(got) != 0 {
+		t.Fatalf("adapterHooks(none) = %q", got)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	out := "one\ntwo  \nthree\n\n\nPane is dead (status 3, Sun Oct 18 14:37:16 2026)\n\n"
+	if got := tailLines(out, 2); !slices.Equal(got, []string{"two", "three"}) {
+		t.Fatalf("tailLines() = %q", got)
+	}
+	if got := tailLines("\n\n", 5); len(got) != 0 {
+		t.Fatalf("tailLines(blank) = %q", got)
+	}
+}
@@ -6,19 +6,74 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
-// PipePaneManager manages pipe-pane output streaming per agent session.
+// FlushPolicy controls how pipe-pane output is batched before it is fanned out.
+// A flush following a quiet period waits only MinInterval, so keystroke echo
+// stays snappy; while flushes keep carrying at least BurstBytes the interval
+// doubles up to MaxInterval, trading latency for fewer, larger frames.
+type FlushPolicy struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	BurstBytes  int
+}
+
+// DefaultFlushPolicy is used for any zero fields of a FlushPolicy.
+var DefaultFlushPolicy = FlushPolicy{
+	MinInterval: 5 * time.Millisecond,
+	MaxInterval: 100 * time.Millisecond,
+	BurstBytes:  8 * 1024,
+}
+
+const (
+	pipeReadBufferSize = 32 * 1024
+	pipeMaxIdlePoll    = 20 * time.Millisecond
+)
+
+// withDefaults fills zero fields from DefaultFlushPolicy and keeps Max >= Min.
+func (p FlushPolicy) withDefaults() FlushPolicy {
+	if p.MinInterval <= 0 {
+		p.MinInterval = DefaultFlushPolicy.MinInterval
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = DefaultFlushPolicy.MaxInterval
+	}
+	if p.MaxInterval < p.MinInterval {
+		p.MaxInterval = p.MinInterval
+	}
+	if p.BurstBytes <= 0 {
+		p.BurstBytes = DefaultFlushPolicy.BurstBytes
+	}
+	return p
+}
+
+// next returns the batching interval to use after flushing n bytes.
+func (p FlushPolicy) next(cur time.Duration, n int) time.Duration {
+	if n < p.BurstBytes {
+		return p.MinInterval
+	}
+	cur *= 2
+	if cur > p.MaxInterval {
+		cur = p.MaxInterval
+	}
+	return cur
+}
+
+// PipePaneManager manages pipe-pane output streaming per agent, or per
+// auxiliary pane keyed by its pane ID.
 type PipePaneManager struct {
 	ctrl    *ControlMode
+	policy  FlushPolicy
+	target  func(name string) string
 	mu      sync.Mutex
 	streams map[string]*pipeStream
 }
 
 type pipeStream struct {
-	session     string
+	target      string // the pane pipe-pane runs on
 	filePath    string
 	cancel      context.CancelFunc
 	subscribers map[chan []byte]struct{}
@@ -26,22 +81,32 @@ type pipeStream struct {
 }
 
 // NewPipePaneManager creates a new pipe-pane manager.
-func NewPipePaneManager(ctrl *ControlMode) *PipePaneManager {
+func NewPipePaneManager(ctrl *ControlMode, policy FlushPolicy) *PipePaneManager {
 	return &PipePaneManager{
 		ctrl:    ctrl,
+		policy:  policy.withDefaults(),
 		streams: make(map[string]*pipeStream),
 	}
 }
 
-// Subscribe starts streaming output for a session and returns a channel for receiving raw bytes.
+// SetTargetFunc installs the lookup from a stream name to the tmux target
+// whose output it carries, e.g. an agent's pane ID. Names are used as
+// targets as they are until it is set.
+func (pm *PipePaneManager) SetTargetFunc(target func(name string) string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.target = target
+}
+
+// Subscribe starts streaming output for an agent or pane ID and returns a channel for receiving raw bytes.
 // If this is the first subscriber, pipe-pane is activated.
-func (pm *PipePaneManager) Subscribe(session string) (<-chan []byte, error) {
+func (pm *PipePaneManager) Subscribe(name string) (<-chan []byte, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
 	ch := make(chan []byte, 256)
 
-	stream, exists := pm.streams[session]
+	stream, exists := pm.streams[name]
 	if exists {
 		stream.mu.Lock()
 		stream.subscribers[ch] = struct{}{}
@@ -50,7 +115,11 @@ func (pm *PipePaneManager) Subscribe(session string) (<-chan []byte, error) {
 	}
 
 	// First subscriber — activate pipe-pane
-	filePath := fmt.Sprintf("/tmp/adapter-%s.pipe", session)
+	filePath := fmt.Sprintf("/tmp/adapter-%s.pipe", strings.ReplaceAll(name, "%", "pane-"))
+	target := name
+	if pm.target != nil {
+		target = pm.target(name)
+	}
 
 	// Create the file if it doesn't exist
 	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -62,7 +131,7 @@ func (pm *PipePaneManager) Subscribe(session string) (<-chan []byte, error) {
 	}
 
 	// Activate pipe-pane
-	if err := pm.ctrl.PipePaneStart(session, fmt.Sprintf("cat >> %s", filePath)); err != nil {
+	if err := pm.ctrl.PipePaneStart(target, fmt.Sprintf("cat >> %s", filePath)); err != nil {
 		if rmErr := os.Remove(filePath); rmErr != nil {
 			log.Printf("pipe-pane cleanup %s: %v", filePath, rmErr)
 		}
@@ -71,12 +140,12 @@ func (pm *PipePaneManager) Subscribe(session string) (<-chan []byte, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	stream = &pipeStream{
-		session:     session,
+		target:      target,
 		filePath:    filePath,
 		cancel:      cancel,
 		subscribers: map[chan []byte]struct{}{ch: {}},
 	}
-	pm.streams[session] = stream
+	pm.streams[name] = stream
 
 	go pm.tailFile(ctx, stream)
 
@@ -84,11 +153,11 @@ func (pm *PipePaneManager) Subscribe(session string) (<-chan []byte, error) {
 }
 
 // Unsubscribe removes a subscriber. If it was the last one, pipe-pane is deactivated.
-func (pm *PipePaneManager) Unsubscribe(session string, ch <-chan []byte) {
+func (pm *PipePaneManager) Unsubscribe(name string, ch <-chan []byte) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	stream, exists := pm.streams[session]
+	stream, exists := pm.streams[name]
 	if !exists {
 		return
 	}
@@ -107,7 +176,7 @@ func (pm *PipePaneManager) Unsubscribe(session string, ch <-chan []byte) {
 
 	if remaining == 0 {
 		pm.stopStream(stream)
-		delete(pm.streams, session)
+		delete(pm.streams, name)
 	}
 }
 
@@ -124,8 +193,8 @@ func (pm *PipePaneManager) StopAll() {
 
 func (pm *PipePaneManager) stopStream(stream *pipeStream) {
 	stream.cancel()
-	if err := pm.ctrl.PipePaneStop(stream.session); err != nil {
-		log.Printf("pipe-pane stop %s: %v", stream.session, err)
+	if err := pm.ctrl.PipePaneStop(stream.target); err != nil {
+		log.Printf("pipe-pane stop %s: %v", stream.target, err)
 	}
 
 	stream.mu.Lock()
@@ -140,7 +209,8 @@ func (pm *PipePaneManager) stopStream(stream *pipeStream) {
 	}
 }
 
-// tailFile reads new bytes from the pipe file and fans out raw bytes to subscribers at ~30fps.
+// tailFile reads new bytes from the pipe file and fans out raw bytes to
+// subscribers, batching according to the manager's FlushPolicy.
 func (pm *PipePaneManager) tailFile(ctx context.Context, stream *pipeStream) {
 	f, err := os.Open(stream.filePath)
 	if err != nil {
@@ -162,12 +232,15 @@ func (pm *PipePaneManager) tailFile(ctx context.Context, stream *pipeStream) {
 	// Pending buffer accumulates raw bytes across multiple reads.
 	var pending []byte
 	var pendingMu sync.Mutex
+	dataReady := make(chan struct{}, 1)
 
-	// Read goroutine: continuously reads raw bytes into buffer
+	// Read goroutine: continuously reads raw bytes into buffer. Polling backs
+	// off while the pane is idle and snaps back to MinInterval on new output.
 	readDone := make(chan struct{})
 	go func() {
 		defer close(readDone)
-		buf := make([]byte, 4096)
+		buf := make([]byte, pipeReadBufferSize)
+		poll := pm.policy.MinInterval
 		for {
 			select {
 			case <-ctx.Done():
@@ -180,21 +253,32 @@ func (pm *PipePaneManager) tailFile(ctx context.Context, stream *pipeStream) {
 				pendingMu.Lock()
 				pending = append(pending, buf[:n]...)
 				pendingMu.Unlock()
+				select {
+				case dataReady <- struct{}{}:
+				default:
+				}
+				poll = pm.policy.MinInterval
 			}
 
 			if err != nil || n == 0 {
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(50 * time.Millisecond):
+				case <-time.After(poll):
+				}
+				if poll *= 2; poll > pipeMaxIdlePoll {
+					poll = pipeMaxIdlePoll
 				}
 			}
 		}
 	}()
 
-	// Send loop: flush accumulated bytes to subscribers at ~30fps
-	ticker := time.NewTicker(33 * time.Millisecond)
-	defer ticker.Stop()
+	// Send loop: once output arrives, wait the current interval to batch
+	// follow-up bytes, then flush to subscribers.
+	interval := pm.policy.MinInterval
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
 
 	for {
 		select {
@@ -202,25 +286,33 @@ func (pm *PipePaneManager) tailFile(ctx context.Context, stream *pipeStream) {
 			return
 		case <-readDone:
 			return
-		case <-ticker.C:
-			pendingMu.Lock()
-			if len(pending) == 0 {
-				pendingMu.Unlock()
-				continue
-			}
-			data := pending
-			pending = nil
-			pendingMu.Unlock()
+		case <-dataReady:
+		}
 
-			stream.mu.Lock()
-			for ch := range stream.subscribers {
-				select {
-				case ch <- data:
-				default:
-					// Subscriber is slow — drop this update
-				}
+		timer.Reset(interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		pendingMu.Lock()
+		data := pending
+		pending = nil
+		pendingMu.Unlock()
+		if len(data) == 0 {
+			continue
+		}
+		interval = pm.policy.next(interval, len(data))
+
+		stream.mu.Lock()
+		for ch := range stream.subscribers {
+			select {
+			case ch <- data:
+			default:
+				// Subscriber is slow — drop this update
 			}
-			stream.mu.Unlock()
 		}
+		stream.mu.Unlock()
 	}
 }
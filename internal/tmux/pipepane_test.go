@@ -0,0 +1,37 @@
+package tmux
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlushPolicyWithDefaults(t *testing.T) {
+	p := FlushPolicy{MinInterval: 50 * time.Millisecond, MaxInterval: 10 * time.Millisecond}.withDefaults()
+	if p.MinInterval != 50*time.Millisecond || p.MaxInterval != 50*time.Millisecond {
+		t.Fatalf("intervals = %v/%v, want max raised to min", p.MinInterval, p.MaxInterval)
+	}
+	if p.BurstBytes != DefaultFlushPolicy.BurstBytes {
+		t.Fatalf("BurstBytes = %d, want default %d", p.BurstBytes, DefaultFlushPolicy.BurstBytes)
+	}
+}
+
+func TestFlushPolicyNextAdaptsToBursts(t *testing.T) {
+	p := FlushPolicy{MinInterval: 5 * time.Millisecond, MaxInterval: 30 * time.Millisecond, BurstBytes: 1000}
+
+	cur := p.MinInterval
+	var got []time.Duration
+	for i := 0; i < 4; i++ {
+		cur = p.next(cur, 5000)
+		got = append(got, cur)
+	}
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("burst intervals = %v, want %v", got, want)
+		}
+	}
+
+	if cur = p.next(cur, 3); cur != p.MinInterval {
+		t.Fatalf("interval after small flush = %v, want %v", cur, p.MinInterval)
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
 
 	"nhooyr.io/websocket"
+
+	"github.com/gastownhall/tmux-adapter/internal/audit"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
 )
 
 // outMsg wraps a WebSocket message with its type (text or binary).
@@ -19,21 +24,29 @@ type outMsg struct {
 type Client struct {
 	conn       *websocket.Conn
 	server     *Server
+	identity   auth.Identity
+	remote     string // remote address, for the audit log
+	label      string // client-chosen name shown in presence
 	send       chan outMsg
 	agentSub   bool                     // subscribed to agent lifecycle
 	outputSubs map[string]<-chan []byte // agent name -> raw byte channel
+	auxSubs    map[string]<-chan []byte // auxiliary pane ID -> raw byte channel
+	watchSince map[string]time.Time     // agent name -> when streaming began
 	mu         sync.Mutex
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
 
 // NewClient creates a new WebSocket client.
-func NewClient(conn *websocket.Conn, server *Server, ctx context.Context, cancel context.CancelFunc) *Client {
+func NewClient(conn *websocket.Conn, server *Server, identity auth.Identity, ctx context.Context, cancel context.CancelFunc) *Client {
 	return &Client{
 		conn:       conn,
 		server:     server,
+		identity:   identity,
 		send:       make(chan outMsg, 256),
 		outputSubs: make(map[string]<-chan []byte),
+		auxSubs:    make(map[string]<-chan []byte),
+		watchSince: make(map[string]time.Time),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -50,7 +63,9 @@ func (c *Client) ReadPump() {
 		}
 
 		if typ == websocket.MessageBinary {
-			handleBinaryMessage(c, data)
+			if c.allow(ratelimit.KindWS, "") {
+				handleBinaryMessage(c, data)
+			}
 			continue
 		}
 
@@ -60,7 +75,9 @@ func (c *Client) ReadPump() {
 			continue
 		}
 
-		handleMessage(c, req)
+		if c.allow(ratelimit.KindWS, req.ID) {
+			handleMessage(c, req)
+		}
 	}
 }
 
@@ -111,6 +128,63 @@ func (c *Client) sendJSON(v any) {
 	c.SendText(data)
 }
 
+// audit records a mutating action by this client.
+func (c *Client) audit(action, agent string, detail map[string]any, err error) {
+	result, errMsg := audit.Outcome(err)
+	c.server.audit.Record(audit.Entry{
+		Actor:  c.identity.Name,
+		Issuer: c.identity.Issuer,
+		Remote: c.remote,
+		Action: action,
+		Agent:  agent,
+		Detail: detail,
+		Result: result,
+		Error:  errMsg,
+	})
+}
+
+// auditDenied records a mutating action rejected by access control.
+func (c *Client) auditDenied(action, agent string, err error) {
+	c.server.audit.Record(audit.Entry{
+		Actor:  c.identity.Name,
+		Issuer: c.identity.Issuer,
+		Remote: c.remote,
+		Action: action,
+		Agent:  agent,
+		Result: audit.ResultDenied,
+		Error:  err.Error(),
+	})
+}
+
+// allow charges one message of kind to the client's rate limit, replying with
+// an error (correlated by id, if any) when it is exceeded.
+func (c *Client) allow(kind ratelimit.Kind, id string) bool {
+	if ok, _ := c.server.limits.Allow(kind, c.identity, c.remote); ok {
+		return true
+	}
+	c.sendError(id, "rate limit exceeded")
+	return false
+}
+
+// watching reports whether the client follows an agent, by streaming its
+// output or by subscribing to lifecycle events.
+func (c *Client) watching(agent string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.outputSubs[agent]
+	return ok || c.agentSub
+}
+
+// sendInputLock tells the client who holds an agent's input lock (nil = nobody).
+func (c *Client) sendInputLock(agent string, holder *Client, reason string) {
+	locked := holder != nil
+	resp := Response{Type: "input-lock-changed", Name: agent, Locked: &locked, Mine: holder == c, Reason: reason}
+	if locked {
+		resp.Holder = holder.identity.Name
+	}
+	c.sendJSON(resp)
+}
+
 // sendError sends an error response.
 func (c *Client) sendError(id, errMsg string) {
 	ok := false
@@ -134,6 +208,11 @@ func (c *Client) Close() {
 	for session, ch := range c.outputSubs {
 		c.server.pipeMgr.Unsubscribe(session, ch)
 		delete(c.outputSubs, session)
+		delete(c.watchSince, session)
+	}
+	for pane, ch := range c.auxSubs {
+		c.server.pipeMgr.Unsubscribe(pane, ch)
+		delete(c.auxSubs, pane)
 	}
 
 	c.agentSub = false
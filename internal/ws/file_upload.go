@@ -2,6 +2,8 @@ package ws
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -20,11 +22,21 @@ const (
 
 // handleBinaryFileUpload stores an uploaded file server-side, copies a pasteable
 // payload to the local clipboard when possible, and pastes into the tmux target.
-func handleBinaryFileUpload(c *Client, agentName string, payload []byte) error {
+func handleBinaryFileUpload(c *Client, agentName string, payload []byte) (err error) {
 	fileName, mimeType, fileBytes, err := parseFileUploadPayload(payload)
 	if err != nil {
+		c.audit("upload", agentName, nil, err)
 		return err
 	}
+	sum := sha256.Sum256(fileBytes)
+	defer func() {
+		c.audit("upload", agentName, map[string]any{
+			"file":   fileName,
+			"mime":   mimeType,
+			"bytes":  len(fileBytes),
+			"sha256": hex.EncodeToString(sum[:]),
+		}, err)
+	}()
 	if len(fileBytes) > maxFileUploadBytes {
 		return fmt.Errorf("file %q too large: %d bytes (max %d)", fileName, len(fileBytes), maxFileUploadBytes)
 	}
@@ -40,7 +52,7 @@ func handleBinaryFileUpload(c *Client, agentName string, payload []byte) error {
 	}
 
 	pasteBaseDir := agent.WorkDir
-	if paneInfo, err := c.server.ctrl.GetPaneInfo(agentName); err == nil && strings.TrimSpace(paneInfo.WorkDir) != "" {
+	if paneInfo, err := c.server.ctrl.GetPaneInfo(agent.Target()); err == nil && strings.TrimSpace(paneInfo.WorkDir) != "" {
 		pasteBaseDir = paneInfo.WorkDir
 	}
 	pastePath := buildServerPastePath(pasteBaseDir, savedPath)
@@ -49,7 +61,7 @@ func handleBinaryFileUpload(c *Client, agentName string, payload []byte) error {
 	if err := copyToLocalClipboard(pastePayload); err != nil {
 		log.Printf("clipboard copy %s: %v", agentName, err)
 	}
-	if err := c.server.ctrl.PasteBytes(agentName, pastePayload); err != nil {
+	if err := c.server.ctrl.PasteBytes(agent.Target(), pastePayload); err != nil {
 		return fmt.Errorf("paste into tmux: %w", err)
 	}
 
@@ -3,6 +3,7 @@ package ws
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -10,7 +11,11 @@ import (
 	"time"
 
 	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/audit"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
 	"github.com/gastownhall/tmux-adapter/internal/nudge"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
 )
 
 // Request is a message from a WebSocket client.
@@ -20,6 +25,14 @@ type Request struct {
 	Agent  string `json:"agent,omitempty"`
 	Prompt string `json:"prompt,omitempty"`
 	Stream *bool  `json:"stream,omitempty"`
+	MaxFps int    `json:"maxFps,omitempty"` // subscribe-output: cap on output frames per second (0 = server cadence)
+	Pane   string `json:"pane,omitempty"`   // subscribe-output, unsubscribe-output: an auxiliary pane ID
+	Force  bool   `json:"force,omitempty"`  // acquire-input: take the lock from its holder (admin)
+	Policy string `json:"policy,omitempty"` // set-resize-policy: largest, smallest, driver-wins, fixed or default
+	Cols   int    `json:"cols,omitempty"`   // set-resize-policy: fixed size
+	Rows   int    `json:"rows,omitempty"`
+	// spawn-agent: role, rig, name, runtime, workDir, command and env.
+	lifecycle.Spec
 }
 
 // Response is a message sent to a WebSocket client.
@@ -33,6 +46,16 @@ type Response struct {
 	Agent   *agents.Agent  `json:"agent,omitempty"`
 	Name    string         `json:"name,omitempty"`
 	Data    string         `json:"data,omitempty"`
+	Locked  *bool          `json:"locked,omitempty"` // input-lock-changed
+	Holder  string         `json:"holder,omitempty"` // identity holding the input lock
+	Mine    bool           `json:"mine,omitempty"`   // the recipient holds the input lock
+	Reason  string         `json:"reason,omitempty"`
+	PrevPID int            `json:"prevPid,omitempty"` // agent-restarted: the replaced pane process
+	PID     int            `json:"pid,omitempty"`     // agent-restarted: the new pane process
+	Crash   *agents.Crash  `json:"crash,omitempty"`   // agent-crashed
+	// Presence maps agent names to their WebSocket viewers (list-agents,
+	// presence-changed).
+	Presence map[string][]Viewer `json:"presence,omitempty"`
 }
 
 // Binary protocol message types
@@ -45,8 +68,51 @@ const (
 )
 
 
+// messageScopes is the scope required for each text message type.
+var messageScopes = map[string]auth.Scope{
+	"list-agents":        auth.ScopeRead,
+	"send-prompt":        auth.ScopeOperate,
+	"subscribe-output":   auth.ScopeRead,
+	"unsubscribe-output": auth.ScopeRead,
+	"subscribe-agents":   auth.ScopeRead,
+	"unsubscribe-agents": auth.ScopeRead,
+	"acquire-input":      auth.ScopeOperate,
+	"release-input":      auth.ScopeOperate,
+	"set-resize-policy":  auth.ScopeOperate,
+	"spawn-agent":        auth.ScopeAdmin,
+}
+
+// auditedMessages maps mutating text message types to audit actions.
+var auditedMessages = map[string]string{
+	"send-prompt":       "prompt",
+	"set-resize-policy": "resize-policy",
+	"spawn-agent":       "spawn",
+}
+
+// binaryScopes is the scope required for each client → server binary frame type.
+var binaryScopes = map[byte]struct {
+	action      string
+	scope       auth.Scope
+	auditAction string // "" = denials are not audited (rejected keystrokes would flood the log)
+}{
+	BinaryKeyboardInput: {"keyboard input", auth.ScopeOperate, ""},
+	BinaryResize:        {"resize", auth.ScopeOperate, "resize"},
+	BinaryFileUpload:    {"file upload", auth.ScopeOperate, "upload"},
+}
+
 // handleMessage routes a text request to the appropriate handler.
 func handleMessage(c *Client, req Request) {
+	if scope, ok := messageScopes[req.Type]; ok {
+		if err := checkAccess(c, req.Type, req.Agent, scope); err != nil {
+			if action, ok := auditedMessages[req.Type]; ok {
+				c.auditDenied(action, req.Agent, err)
+			}
+			okVal := false
+			c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+			return
+		}
+	}
+
 	switch req.Type {
 	case "list-agents":
 		handleListAgents(c, req)
@@ -60,6 +126,14 @@ func handleMessage(c *Client, req Request) {
 		handleSubscribeAgents(c, req)
 	case "unsubscribe-agents":
 		handleUnsubscribeAgents(c, req)
+	case "acquire-input":
+		handleAcquireInput(c, req)
+	case "release-input":
+		handleReleaseInput(c, req)
+	case "set-resize-policy":
+		handleSetResizePolicy(c, req)
+	case "spawn-agent":
+		handleSpawnAgent(c, req)
 	default:
 		c.sendError(req.ID, "unknown message type: "+req.Type)
 	}
@@ -74,12 +148,39 @@ func handleBinaryMessage(c *Client, data []byte) {
 		return
 	}
 
+	if rule, ok := binaryScopes[msgType]; ok {
+		action := rule.action + " " + agentName
+		if err := checkAccess(c, action, agentName, rule.scope); err != nil {
+			if rule.auditAction != "" {
+				c.auditDenied(rule.auditAction, agentName, err)
+			}
+			if errors.Is(err, errAgentNotFound) {
+				err = fmt.Errorf("%s: %w", action, err)
+			}
+			c.sendError("", err.Error())
+			return
+		}
+		if err := c.server.inputLocks.check(agentName, c); err != nil {
+			if msgType == BinaryResize {
+				return // viewers follow the driver's window size
+			}
+			if rule.auditAction != "" {
+				c.auditDenied(rule.auditAction, agentName, err)
+			}
+			c.sendError("", err.Error())
+			return
+		}
+	}
+
 	switch msgType {
 	case BinaryKeyboardInput:
 		if err := sendKeyboardPayload(c, agentName, payload); err != nil {
 			log.Printf("keyboard input %s error: %v", agentName, err)
 			c.sendError("", "keyboard input "+agentName+": "+err.Error())
+			c.audit("keys", agentName, map[string]any{"bytes": len(payload)}, err)
+			return
 		}
+		c.server.audit.CountKeys(c.identity.Name, c.remote, agentName, len(payload))
 	case BinaryResize:
 		parts := strings.SplitN(string(payload), ":", 2)
 		if len(parts) != 2 {
@@ -97,13 +198,18 @@ func handleBinaryMessage(c *Client, data []byte) {
 			return
 		}
 		log.Printf("binary resize %s -> %dx%d", agentName, cols, rows)
-		if err := c.server.ctrl.ResizePaneTo(agentName, cols, rows); err != nil {
+		err := c.server.requestResize(c, agentName, cols, rows)
+		c.audit("resize", agentName, map[string]any{"cols": cols, "rows": rows}, err)
+		if err != nil {
 			log.Printf("resize %s error: %v", agentName, err)
 			c.sendError("", "resize "+agentName+": "+err.Error())
 			return
 		}
 		// No snapshot needed — pipe-pane captures the app's SIGWINCH redraw naturally.
 	case BinaryFileUpload:
+		if !c.allow(ratelimit.KindUpload, "") {
+			return
+		}
 		payloadCopy := append([]byte(nil), payload...)
 		go func() {
 			lock := nudge.GetLock(agentName)
@@ -121,6 +227,37 @@ func handleBinaryMessage(c *Client, data []byte) {
 	}
 }
 
+// errAgentNotFound is reported for agents outside the client's ACL, so they
+// are indistinguishable from agents that do not exist.
+var errAgentNotFound = errors.New("agent not found")
+
+// checkAccess verifies the client may perform action on agentName (which may
+// be empty for agent-independent actions). Agents that are not running are
+// checked by their session name; handlers report them missing themselves.
+func checkAccess(c *Client, action, agentName string, scope auth.Scope) error {
+	if !c.identity.Scope.Allows(scope) {
+		return forbiddenError(action, scope)
+	}
+	if agentName == "" {
+		return nil
+	}
+	ref := auth.SessionRef(agentName)
+	if agent, ok := c.server.registry.GetAgent(agentName); ok {
+		ref = auth.RefOf(agent)
+	}
+	switch granted := c.identity.ScopeFor(ref); {
+	case !granted.Allows(auth.ScopeRead):
+		return errAgentNotFound
+	case !granted.Allows(scope):
+		return forbiddenError(action, scope)
+	}
+	return nil
+}
+
+func forbiddenError(action string, scope auth.Scope) error {
+	return fmt.Errorf("forbidden: %s requires %s scope", action, scope)
+}
+
 func parseBinaryEnvelope(data []byte) (msgType byte, agentName string, payload []byte, err error) {
 	if len(data) < 3 {
 		return 0, "", nil, fmt.Errorf("frame too short")
@@ -142,12 +279,17 @@ func parseBinaryEnvelope(data []byte) (msgType byte, agentName string, payload [
 }
 
 func sendKeyboardPayload(c *Client, agentName string, payload []byte) error {
+	// Only agent panes take input; auxiliary panes are read-only.
+	agent, ok := c.server.registry.GetAgent(agentName)
+	if !ok {
+		return errAgentNotFound
+	}
 	// Prefer tmux key names for known VT special-key sequences (e.g. Shift+Tab).
 	// Fall back to byte-exact injection for everything else.
 	if keyName, ok := tmuxKeyNameFromVT(payload); ok {
-		return c.server.ctrl.SendKeysRaw(agentName, keyName)
+		return c.server.ctrl.SendKeysRaw(agent.Target(), keyName)
 	}
-	return c.server.ctrl.SendKeysBytes(agentName, payload)
+	return c.server.ctrl.SendKeysBytes(agent.Target(), payload)
 }
 
 func tmuxKeyNameFromVT(payload []byte) (string, bool) {
@@ -218,11 +360,16 @@ func makeBinaryFrame(msgType byte, agentName string, payload []byte) []byte {
 }
 
 func handleListAgents(c *Client, req Request) {
-	agentList := c.server.registry.GetAgents()
+	agentList := auth.VisibleAgents(c.identity, c.server.registry.GetAgents())
+	names := make([]string, len(agentList))
+	for i, a := range agentList {
+		names[i] = a.Name
+	}
 	c.sendJSON(Response{
-		ID:     req.ID,
-		Type:   "list-agents",
-		Agents: agentList,
+		ID:       req.ID,
+		Type:     "list-agents",
+		Agents:   agentList,
+		Presence: c.server.presenceFor(names),
 	})
 }
 
@@ -243,6 +390,12 @@ func handleSendPrompt(c *Client, req Request) {
 		c.sendJSON(Response{ID: req.ID, Type: "send-prompt", OK: &ok, Error: "agent not found"})
 		return
 	}
+	if err := c.server.inputLocks.check(req.Agent, c); err != nil {
+		c.auditDenied("prompt", req.Agent, err)
+		ok := false
+		c.sendJSON(Response{ID: req.ID, Type: "send-prompt", OK: &ok, Error: err.Error()})
+		return
+	}
 
 	// Serialize sends to this agent
 	lock := nudge.GetLock(req.Agent)
@@ -251,7 +404,12 @@ func handleSendPrompt(c *Client, req Request) {
 		lock.Lock()
 		defer lock.Unlock()
 
-		if err := nudge.Session(c.server.ctrl, agent, req.Prompt); err != nil {
+		if c.server.viewersAttach && c.server.Watched(agent.Name) {
+			agent.Attached = true // someone is watching; no need to wake the pane
+		}
+		err := nudge.Session(c.server.ctrl, agent, req.Prompt)
+		c.audit("prompt", req.Agent, audit.PromptDetail(req.Prompt), err)
+		if err != nil {
 			ok := false
 			c.sendJSON(Response{ID: req.ID, Type: "send-prompt", OK: &ok, Error: err.Error()})
 			return
@@ -268,7 +426,7 @@ func handleSubscribeOutput(c *Client, req Request) {
 		return
 	}
 
-	_, ok := c.server.registry.GetAgent(req.Agent)
+	agent, ok := c.server.registry.GetAgent(req.Agent)
 	if !ok {
 		okVal := false
 		c.sendJSON(Response{ID: req.ID, Type: "subscribe-output", OK: &okVal, Error: "agent not found"})
@@ -278,6 +436,16 @@ func handleSubscribeOutput(c *Client, req Request) {
 	// Check if streaming is requested (default: true)
 	wantStream := req.Stream == nil || *req.Stream
 
+	if req.MaxFps < 0 || req.MaxFps > maxOutputFps {
+		c.sendError(req.ID, fmt.Sprintf("maxFps must be between 0 and %d", maxOutputFps))
+		return
+	}
+
+	if req.Pane != "" {
+		subscribeAuxPane(c, req, agent, wantStream)
+		return
+	}
+
 	if wantStream {
 		// Subscribe to pipe-pane first so it's ready for ongoing streaming.
 		log.Printf("subscribe-output(%s): starting pipe-pane", req.Agent)
@@ -292,7 +460,12 @@ func handleSubscribeOutput(c *Client, req Request) {
 
 		c.mu.Lock()
 		c.outputSubs[req.Agent] = ch
+		if _, ok := c.watchSince[req.Agent]; !ok {
+			c.watchSince[req.Agent] = time.Now()
+		}
 		c.mu.Unlock()
+		c.server.broadcastPresence(req.Agent)
+		c.server.resizeAgents(req.Agent) // a fixed size applies as soon as someone watches
 
 		okVal := true
 		c.sendJSON(Response{
@@ -300,6 +473,9 @@ func handleSubscribeOutput(c *Client, req Request) {
 			Type: "subscribe-output",
 			OK:   &okVal,
 		})
+		if holder := c.server.inputLocks.holder(req.Agent); holder != nil {
+			c.sendInputLock(req.Agent, holder, "")
+		}
 
 		// Drain any output the agent was already producing — we only want
 		// the controlled redraw.
@@ -323,7 +499,7 @@ func handleSubscribeOutput(c *Client, req Request) {
 		// Force a clean redraw. The resize dance triggers SIGWINCH, causing
 		// the app to repaint. pipe-pane captures all output in real-time.
 		log.Printf("subscribe-output(%s): forcing redraw", req.Agent)
-		c.server.ctrl.ForceRedraw(req.Agent)
+		c.server.ctrl.ForceRedraw(agent.Target())
 
 		// Let the app finish redrawing; pipe-pane buffers all output in ch.
 		time.Sleep(200 * time.Millisecond)
@@ -334,14 +510,12 @@ func handleSubscribeOutput(c *Client, req Request) {
 		c.SendBinary(makeBinaryFrame(BinaryTerminalSnapshot, req.Agent, []byte("\x1b[2J\x1b[H")))
 
 		// Stream raw bytes in background — immediately flushes buffered pipe-pane data.
-		go func() {
-			for rawBytes := range ch {
-				c.SendBinary(makeBinaryFrame(BinaryTerminalOutput, req.Agent, rawBytes))
-			}
-		}()
+		go forwardOutput(ch, req.MaxFps, func(rawBytes []byte) {
+			c.SendBinary(makeBinaryFrame(BinaryTerminalOutput, req.Agent, rawBytes))
+		})
 	} else {
 		// Non-streaming: return full capture in JSON
-		fullHistory, _ := c.server.ctrl.CapturePaneAll(req.Agent)
+		fullHistory, _ := c.server.ctrl.CapturePaneAll(agent.Target())
 		okVal := true
 		c.sendJSON(Response{
 			ID:      req.ID,
@@ -352,21 +526,144 @@ func handleSubscribeOutput(c *Client, req Request) {
 	}
 }
 
+// subscribeAuxPane streams one of an agent's auxiliary panes as a read-only
+// sub-terminal. Its binary frames are addressed to auxFrameName; viewers of
+// it do not count towards presence or window sizing, and since only agent
+// panes take input, frames sent back to that name are rejected.
+func subscribeAuxPane(c *Client, req Request, agent agents.Agent, wantStream bool) {
+	if _, ok := agent.AuxPane(req.Pane); !ok {
+		okVal := false
+		c.sendJSON(Response{ID: req.ID, Type: "subscribe-output", OK: &okVal, Error: "pane not found"})
+		return
+	}
+
+	if !wantStream {
+		fullHistory, _ := c.server.ctrl.CapturePaneAll(req.Pane)
+		okVal := true
+		c.sendJSON(Response{ID: req.ID, Type: "subscribe-output", OK: &okVal, History: fullHistory})
+		return
+	}
+
+	ch, err := c.server.pipeMgr.Subscribe(req.Pane)
+	if err != nil {
+		log.Printf("subscribe-output(%s %s): pipe-pane error: %v", req.Agent, req.Pane, err)
+		okVal := false
+		c.sendJSON(Response{ID: req.ID, Type: "subscribe-output", OK: &okVal, Error: err.Error()})
+		return
+	}
+	c.mu.Lock()
+	prev, resubscribed := c.auxSubs[req.Pane]
+	c.auxSubs[req.Pane] = ch
+	c.mu.Unlock()
+	if resubscribed {
+		c.server.pipeMgr.Unsubscribe(req.Pane, prev)
+	}
+
+	okVal := true
+	c.sendJSON(Response{ID: req.ID, Type: "subscribe-output", OK: &okVal})
+
+	// Redrawing would resize the window under the agent too, so start from
+	// the pane's current screen instead.
+	name := auxFrameName(req.Agent, req.Pane)
+	screen, err := c.server.ctrl.CapturePaneVisible(req.Pane)
+	if err != nil {
+		log.Printf("subscribe-output(%s): capture: %v", name, err)
+	}
+	snapshot := "\x1b[2J\x1b[H" + strings.ReplaceAll(strings.TrimRight(screen, "\n"), "\n", "\r\n")
+	c.SendBinary(makeBinaryFrame(BinaryTerminalSnapshot, name, []byte(snapshot)))
+
+	go forwardOutput(ch, req.MaxFps, func(rawBytes []byte) {
+		c.SendBinary(makeBinaryFrame(BinaryTerminalOutput, name, rawBytes))
+	})
+}
+
+// auxFrameName addresses an auxiliary pane's binary frames. tmux session
+// names cannot contain ':', so it never collides with an agent name.
+func auxFrameName(agent, pane string) string {
+	return agent + ":" + pane
+}
+
+// maxOutputFps bounds the maxFps a client may request.
+const maxOutputFps = 120
+
+// forwardOutput passes pipe-pane chunks to emit until ch closes. With maxFps > 0,
+// chunks arriving faster than the client's frame budget are merged so that at
+// most maxFps frames are emitted per second; nothing is dropped.
+func forwardOutput(ch <-chan []byte, maxFps int, emit func([]byte)) {
+	if maxFps <= 0 {
+		for rawBytes := range ch {
+			emit(rawBytes)
+		}
+		return
+	}
+
+	minGap := time.Second / time.Duration(maxFps)
+	var pending []byte
+	var last time.Time
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+	var timerC <-chan time.Time
+
+	for {
+		select {
+		case rawBytes, ok := <-ch:
+			if !ok {
+				if len(pending) > 0 {
+					emit(pending)
+				}
+				return
+			}
+			pending = append(pending, rawBytes...)
+			if timerC != nil {
+				continue // a flush is already scheduled
+			}
+			if wait := minGap - time.Since(last); wait > 0 {
+				timer.Reset(wait)
+				timerC = timer.C
+				continue
+			}
+		case <-timerC:
+			timerC = nil
+		}
+		emit(pending)
+		pending = nil
+		last = time.Now()
+	}
+}
+
 func handleUnsubscribeOutput(c *Client, req Request) {
 	if req.Agent == "" {
 		c.sendError(req.ID, "agent field required")
 		return
 	}
 
+	if req.Pane != "" {
+		c.mu.Lock()
+		ch, exists := c.auxSubs[req.Pane]
+		delete(c.auxSubs, req.Pane)
+		c.mu.Unlock()
+		if exists {
+			c.server.pipeMgr.Unsubscribe(req.Pane, ch)
+		}
+		okVal := true
+		c.sendJSON(Response{ID: req.ID, Type: "unsubscribe-output", OK: &okVal})
+		return
+	}
+
 	c.mu.Lock()
 	ch, exists := c.outputSubs[req.Agent]
 	if exists {
 		delete(c.outputSubs, req.Agent)
+		delete(c.watchSince, req.Agent)
 	}
 	c.mu.Unlock()
 
 	if exists {
 		c.server.pipeMgr.Unsubscribe(req.Agent, ch)
+		c.server.broadcastPresence(req.Agent)
+		c.server.resizes.forget(req.Agent, c)
+		c.server.resizeAgents(req.Agent)
 	}
 
 	okVal := true
@@ -378,7 +675,7 @@ func handleSubscribeAgents(c *Client, req Request) {
 	c.agentSub = true
 	c.mu.Unlock()
 
-	agentList := c.server.registry.GetAgents()
+	agentList := auth.VisibleAgents(c.identity, c.server.registry.GetAgents())
 	okVal := true
 	c.sendJSON(Response{
 		ID:     req.ID,
@@ -397,16 +694,135 @@ func handleUnsubscribeAgents(c *Client, req Request) {
 	c.sendJSON(Response{ID: req.ID, Type: "unsubscribe-agents", OK: &okVal})
 }
 
+// handleAcquireInput makes the client the driver of an agent: until it sends
+// release-input, disconnects or goes idle, other clients are read-only for it.
+func handleAcquireInput(c *Client, req Request) {
+	if req.Agent == "" {
+		c.sendError(req.ID, "agent field required")
+		return
+	}
+	if _, ok := c.server.registry.GetAgent(req.Agent); !ok {
+		okVal := false
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: "agent not found"})
+		return
+	}
+	if req.Force {
+		if err := checkAccess(c, "forced acquire-input", req.Agent, auth.ScopeAdmin); err != nil {
+			okVal := false
+			c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+			return
+		}
+	}
+
+	if err := c.server.inputLocks.acquire(req.Agent, c, req.Force); err != nil {
+		okVal := false
+		resp := Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()}
+		var locked *inputLockedError
+		if errors.As(err, &locked) {
+			resp.Holder = locked.holder
+		}
+		c.sendJSON(resp)
+		return
+	}
+	okVal := true
+	c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal})
+}
+
+func handleReleaseInput(c *Client, req Request) {
+	if req.Agent == "" {
+		c.sendError(req.ID, "agent field required")
+		return
+	}
+	okVal := c.server.inputLocks.release(req.Agent, c)
+	resp := Response{ID: req.ID, Type: req.Type, OK: &okVal}
+	if !okVal {
+		resp.Error = "input lock on " + req.Agent + " is not held by this connection"
+	}
+	c.sendJSON(resp)
+}
+
+func handleSetResizePolicy(c *Client, req Request) {
+	if req.Agent == "" {
+		c.sendError(req.ID, "agent field required")
+		return
+	}
+	okVal := false
+	if _, ok := c.server.registry.GetAgent(req.Agent); !ok {
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: "agent not found"})
+		return
+	}
+	var policy ResizePolicy
+	if req.Policy != "" && req.Policy != "default" {
+		p, err := ParseResizePolicy(req.Policy)
+		if err != nil {
+			c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+			return
+		}
+		policy = p
+	}
+	if policy == ResizeFixed && (req.Cols < 2 || req.Rows < 1) {
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: "fixed policy requires cols >= 2 and rows >= 1"})
+		return
+	}
+
+	c.server.resizes.setPolicy(req.Agent, policy, termSize{req.Cols, req.Rows})
+	err := c.server.applySize(req.Agent)
+	detail := map[string]any{"policy": req.Policy}
+	if policy == ResizeFixed {
+		detail["cols"], detail["rows"] = req.Cols, req.Rows
+	}
+	c.audit("resize-policy", req.Agent, detail, err)
+	if err != nil {
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+		return
+	}
+	okVal = true
+	c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal})
+}
+
+func handleSpawnAgent(c *Client, req Request) {
+	okVal := false
+	name, err := req.Spec.SessionName()
+	if err != nil {
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+		return
+	}
+	if !c.identity.ScopeFor(auth.SessionRef(name)).Allows(auth.ScopeAdmin) {
+		err := forbiddenError(req.Type, auth.ScopeAdmin)
+		c.auditDenied("spawn", name, err)
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Error: err.Error()})
+		return
+	}
+
+	// Waiting for the agent can take a while; keep reading other messages.
+	go func() {
+		agent, err := c.server.life.Spawn(c.ctx, req.Spec)
+		c.audit("spawn", name, req.Spec.AuditDetail(), err)
+		if err != nil {
+			okVal := false
+			c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Name: name, Error: err.Error()})
+			return
+		}
+		okVal := true
+		c.sendJSON(Response{ID: req.ID, Type: req.Type, OK: &okVal, Agent: &agent})
+	}()
+}
+
 // MakeAgentEvent creates a JSON event message for agent lifecycle changes.
-func MakeAgentEvent(eventType string, agent agents.Agent) []byte {
+func MakeAgentEvent(event agents.RegistryEvent) []byte {
+	agent := event.Agent
 	var resp Response
-	switch eventType {
+	switch event.Type {
 	case "added":
 		resp = Response{Type: "agent-added", Agent: &agent}
 	case "removed":
 		resp = Response{Type: "agent-removed", Name: agent.Name}
 	case "updated":
 		resp = Response{Type: "agent-updated", Agent: &agent}
+	case "restarted":
+		resp = Response{Type: "agent-restarted", Agent: &agent, PrevPID: event.PrevPID, PID: event.PID}
+	case "crashed":
+		resp = Response{Type: "agent-crashed", Agent: &agent, Crash: event.Crash}
 	}
 	data, _ := json.Marshal(resp)
 	return data
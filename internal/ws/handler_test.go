@@ -1,6 +1,15 @@
 package ws
 
-import "testing"
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/audit"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
 
 func TestTmuxKeyNameFromVT(t *testing.T) {
 	cases := []struct {
@@ -75,3 +84,87 @@ func TestParseBinaryEnvelopeErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardOutputPassThrough(t *testing.T) {
+	ch := make(chan []byte, 3)
+	ch <- []byte("a")
+	ch <- []byte("b")
+	close(ch)
+
+	var frames []string
+	forwardOutput(ch, 0, func(b []byte) { frames = append(frames, string(b)) })
+	if len(frames) != 2 || frames[0] != "a" || frames[1] != "b" {
+		t.Fatalf("frames = %q, want [a b]", frames)
+	}
+}
+
+func TestForwardOutputCoalescesToMaxFps(t *testing.T) {
+	ch := make(chan []byte)
+	frames := make(chan string, 100)
+	done := make(chan struct{})
+	go func() {
+		forwardOutput(ch, 10, func(b []byte) { frames <- string(b) })
+		close(done)
+	}()
+
+	// 20 chunks over ~200ms at 10fps: the first is sent immediately, the
+	// rest are merged into at most a few frames.
+	for i := 0; i < 20; i++ {
+		ch <- []byte{byte('a' + i)}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(ch)
+	<-done
+	close(frames)
+
+	var all string
+	n := 0
+	for f := range frames {
+		all += f
+		n++
+	}
+	if all != "abcdefghijklmnopqrst" {
+		t.Fatalf("concatenated output = %q, want every chunk in order", all)
+	}
+	if n > 4 {
+		t.Fatalf("sent %d frames for ~200ms of output at 10fps, want <= 4", n)
+	}
+}
+
+func TestReadScopeCannotOperate(t *testing.T) {
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	c := &Client{
+		server:   &Server{audit: auditLog},
+		send:     make(chan outMsg, 4),
+		identity: auth.Identity{Name: "observer", Scope: auth.ScopeRead},
+	}
+
+	handleMessage(c, Request{ID: "1", Type: "send-prompt", Agent: "hq-mayor", Prompt: "rm -rf /"})
+	var resp Response
+	if err := json.Unmarshal((<-c.send).data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "1" || resp.OK == nil || *resp.OK || !strings.Contains(resp.Error, "requires operate scope") {
+		t.Fatalf("send-prompt response = %+v, want forbidden", resp)
+	}
+
+	handleBinaryMessage(c, append([]byte{BinaryKeyboardInput}, "hq-mayor\x00ls\r"...))
+	if err := json.Unmarshal((<-c.send).data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Error, "forbidden: keyboard input hq-mayor") {
+		t.Fatalf("keyboard response = %+v, want forbidden", resp)
+	}
+
+	entries, err := auditLog.Query(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != "prompt" || entries[0].Result != audit.ResultDenied || entries[0].Actor != "observer" {
+		t.Fatalf("audit entries = %+v, want one denied prompt", entries)
+	}
+}
@@ -0,0 +1,194 @@
+package ws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultInputLockTimeout releases an input lock whose holder has sent no
+// input for this long.
+const DefaultInputLockTimeout = 5 * time.Minute
+
+// Reasons reported in input-lock-changed events.
+const (
+	lockAcquired     = "acquired"
+	lockReleased     = "released"
+	lockTimeout      = "timeout"
+	lockDisconnected = "disconnected"
+	lockTakenOver    = "taken-over"
+	lockAgentGone    = "agent-removed"
+)
+
+// inputLockedError is returned when another client holds an agent's input.
+type inputLockedError struct {
+	agent  string
+	holder string
+}
+
+func (e *inputLockedError) Error() string {
+	return fmt.Sprintf("input to %s is locked by %s", e.agent, e.holder)
+}
+
+// inputLocks tracks which client, if any, is driving each agent. While a
+// client holds an agent's lock, other clients are read-only for that agent.
+// A nil *inputLocks never locks anything.
+type inputLocks struct {
+	timeout  time.Duration
+	onChange func(agent string, holder, prev *Client, reason string) // called without mu held
+
+	mu    sync.Mutex
+	locks map[string]*inputLock
+}
+
+type inputLock struct {
+	holder *Client
+	timer  *time.Timer
+}
+
+func newInputLocks(timeout time.Duration, onChange func(agent string, holder, prev *Client, reason string)) *inputLocks {
+	if timeout <= 0 {
+		timeout = DefaultInputLockTimeout
+	}
+	return &inputLocks{
+		timeout:  timeout,
+		onChange: onChange,
+		locks:    make(map[string]*inputLock),
+	}
+}
+
+// acquire gives c the lock on agent. If another client holds it, acquire
+// fails unless force is set, in which case the lock is taken over.
+func (l *inputLocks) acquire(agent string, c *Client, force bool) error {
+	l.mu.Lock()
+	reason := lockAcquired
+	var prev *Client
+	if cur, ok := l.locks[agent]; ok {
+		if cur.holder == c {
+			cur.timer.Reset(l.timeout)
+			l.mu.Unlock()
+			return nil
+		}
+		if !force {
+			l.mu.Unlock()
+			return &inputLockedError{agent: agent, holder: cur.holder.identity.Name}
+		}
+		cur.timer.Stop()
+		prev, reason = cur.holder, lockTakenOver
+	}
+	lock := &inputLock{holder: c}
+	lock.timer = time.AfterFunc(l.timeout, func() { l.expire(agent, lock) })
+	l.locks[agent] = lock
+	l.mu.Unlock()
+
+	l.onChange(agent, c, prev, reason)
+	return nil
+}
+
+// release drops c's lock on agent and reports whether c held it.
+func (l *inputLocks) release(agent string, c *Client) bool {
+	l.mu.Lock()
+	cur, ok := l.locks[agent]
+	if !ok || cur.holder != c {
+		l.mu.Unlock()
+		return false
+	}
+	cur.timer.Stop()
+	delete(l.locks, agent)
+	l.mu.Unlock()
+
+	l.onChange(agent, nil, c, lockReleased)
+	return true
+}
+
+// releaseClient drops every lock held by a disconnecting client.
+func (l *inputLocks) releaseClient(c *Client) {
+	if l == nil {
+		return
+	}
+	var released []string
+	l.mu.Lock()
+	for agent, cur := range l.locks {
+		if cur.holder == c {
+			cur.timer.Stop()
+			delete(l.locks, agent)
+			released = append(released, agent)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, agent := range released {
+		l.onChange(agent, nil, nil, lockDisconnected)
+	}
+}
+
+// releaseAgent drops the lock on an agent that no longer exists.
+func (l *inputLocks) releaseAgent(agent string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	cur, ok := l.locks[agent]
+	if ok {
+		cur.timer.Stop()
+		delete(l.locks, agent)
+	}
+	l.mu.Unlock()
+
+	if ok {
+		l.onChange(agent, nil, cur.holder, lockAgentGone)
+	}
+}
+
+func (l *inputLocks) expire(agent string, lock *inputLock) {
+	l.mu.Lock()
+	if l.locks[agent] != lock {
+		l.mu.Unlock()
+		return
+	}
+	delete(l.locks, agent)
+	l.mu.Unlock()
+
+	l.onChange(agent, nil, lock.holder, lockTimeout)
+}
+
+// check reports whether c may send input to agent. Input from the holder
+// extends its lease.
+func (l *inputLocks) check(agent string, c *Client) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.locks[agent]
+	if !ok {
+		return nil
+	}
+	if cur.holder != c {
+		return &inputLockedError{agent: agent, holder: cur.holder.identity.Name}
+	}
+	cur.timer.Reset(l.timeout)
+	return nil
+}
+
+// InputLockHolder returns the name of the identity holding an agent's input
+// lock, if any.
+func (s *Server) InputLockHolder(agent string) (string, bool) {
+	if holder := s.inputLocks.holder(agent); holder != nil {
+		return holder.identity.Name, true
+	}
+	return "", false
+}
+
+// holder returns the client holding agent's lock, or nil.
+func (l *inputLocks) holder(agent string) *Client {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cur, ok := l.locks[agent]; ok {
+		return cur.holder
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+type lockChange struct {
+	agent  string
+	holder *Client
+	prev   *Client
+	reason string
+}
+
+type lockRecorder struct {
+	mu      sync.Mutex
+	changes []lockChange
+}
+
+func (r *lockRecorder) record(agent string, holder, prev *Client, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, lockChange{agent, holder, prev, reason})
+}
+
+func (r *lockRecorder) last() lockChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changes[len(r.changes)-1]
+}
+
+func TestInputLockMakesOthersReadOnly(t *testing.T) {
+	rec := &lockRecorder{}
+	locks := newInputLocks(time.Minute, rec.record)
+	alice := &Client{identity: auth.Identity{Name: "alice"}}
+	bob := &Client{identity: auth.Identity{Name: "bob"}}
+
+	if err := locks.check("hq-mayor", bob); err != nil {
+		t.Fatalf("unlocked agent: check() = %v", err)
+	}
+	if err := locks.acquire("hq-mayor", alice, false); err != nil {
+		t.Fatalf("acquire() = %v", err)
+	}
+	if got := rec.last(); got.holder != alice || got.reason != lockAcquired {
+		t.Fatalf("change = %+v, want acquired by alice", got)
+	}
+
+	var locked *inputLockedError
+	if err := locks.check("hq-mayor", bob); !errors.As(err, &locked) || locked.holder != "alice" {
+		t.Fatalf("check(bob) = %v, want locked by alice", err)
+	}
+	if err := locks.check("hq-mayor", alice); err != nil {
+		t.Fatalf("check(holder) = %v", err)
+	}
+	if err := locks.check("hq-deacon", bob); err != nil {
+		t.Fatalf("locks must be per agent: %v", err)
+	}
+	if err := locks.acquire("hq-mayor", bob, false); err == nil {
+		t.Fatal("acquire() of a held lock must fail without force")
+	}
+	if locks.release("hq-mayor", bob) {
+		t.Fatal("release() by a non-holder must fail")
+	}
+
+	if err := locks.acquire("hq-mayor", bob, true); err != nil {
+		t.Fatalf("forced acquire() = %v", err)
+	}
+	if got := rec.last(); got.holder != bob || got.prev != alice || got.reason != lockTakenOver {
+		t.Fatalf("change = %+v, want taken over from alice by bob", got)
+	}
+
+	locks.releaseClient(bob)
+	if got := rec.last(); got.holder != nil || got.reason != lockDisconnected {
+		t.Fatalf("change = %+v, want released on disconnect", got)
+	}
+	if locks.holder("hq-mayor") != nil {
+		t.Fatal("lock survived its holder disconnecting")
+	}
+}
+
+func TestInputLockTimesOutWithoutInput(t *testing.T) {
+	rec := &lockRecorder{}
+	locks := newInputLocks(50*time.Millisecond, rec.record)
+	alice := &Client{identity: auth.Identity{Name: "alice"}}
+	if err := locks.acquire("hq-mayor", alice, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Input from the holder extends the lease.
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		if err := locks.check("hq-mayor", alice); err != nil {
+			t.Fatalf("lock expired despite input: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for locks.holder("hq-mayor") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("idle lock never timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := rec.last(); got.reason != lockTimeout {
+		t.Fatalf("change = %+v, want timeout", got)
+	}
+}
+
+func TestNilInputLocksAllowInput(t *testing.T) {
+	var locks *inputLocks
+	if err := locks.check("hq-mayor", &Client{}); err != nil {
+		t.Fatalf("check() = %v", err)
+	}
+}
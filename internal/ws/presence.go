@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+// maxLabelLen bounds the ?label= a client may give itself.
+const maxLabelLen = 64
+
+// Viewer is a WebSocket client streaming an agent's output.
+type Viewer struct {
+	Name    string    `json:"name"`            // identity
+	Label   string    `json:"label,omitempty"` // from ?label= on connect
+	Since   time.Time `json:"since"`
+	Driving bool      `json:"driving,omitempty"` // holds the agent's input lock
+}
+
+// clientLabel sanitizes a client-chosen label.
+func clientLabel(raw string) string {
+	label := strings.TrimSpace(raw)
+	if r := []rune(label); len(r) > maxLabelLen {
+		label = string(r[:maxLabelLen])
+	}
+	return label
+}
+
+// Presence returns the WebSocket clients streaming an agent's output, oldest
+// first.
+func (s *Server) Presence(agent string) []Viewer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.presenceLocked(agent)
+}
+
+// Watched reports whether any WebSocket client is streaming an agent's output.
+func (s *Server) Watched(agent string) bool {
+	return len(s.Presence(agent)) > 0
+}
+
+// presenceLocked is Presence with s.mu held.
+func (s *Server) presenceLocked(agent string) []Viewer {
+	driver := s.inputLocks.holder(agent)
+	viewers := []Viewer{}
+	for client := range s.clients {
+		client.mu.Lock()
+		since, ok := client.watchSince[agent]
+		client.mu.Unlock()
+		if ok {
+			viewers = append(viewers, Viewer{
+				Name:    client.identity.Name,
+				Label:   client.label,
+				Since:   since,
+				Driving: client == driver,
+			})
+		}
+	}
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].Since.Before(viewers[j].Since) })
+	return viewers
+}
+
+// broadcastPresence sends presence-changed to clients watching an agent or
+// subscribed to lifecycle events.
+func (s *Server) broadcastPresence(agent string) {
+	ref := s.agentRef(agent)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, err := json.Marshal(Response{
+		Type:     "presence-changed",
+		Name:     agent,
+		Presence: map[string][]Viewer{agent: s.presenceLocked(agent)},
+	})
+	if err != nil {
+		return
+	}
+	for client := range s.clients {
+		if client.watching(agent) && client.identity.CanSee(ref) {
+			client.SendText(msg)
+		}
+	}
+}
+
+// presenceFor returns the viewers of each watched agent in list.
+func (s *Server) presenceFor(names []string) map[string][]Viewer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	presence := make(map[string][]Viewer)
+	for _, name := range names {
+		if viewers := s.presenceLocked(name); len(viewers) > 0 {
+			presence[name] = viewers
+		}
+	}
+	return presence
+}
+
+// agentRef returns an agent's access-control identity, even after it is gone.
+func (s *Server) agentRef(name string) auth.AgentRef {
+	if a, ok := s.registry.GetAgent(name); ok {
+		return auth.RefOf(a)
+	}
+	return auth.SessionRef(name)
+}
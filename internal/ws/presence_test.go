@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+func TestPresenceListsStreamingClients(t *testing.T) {
+	now := time.Now()
+	alice := &Client{identity: auth.Identity{Name: "alice"}, label: "dashboard", watchSince: map[string]time.Time{"hq-mayor": now}}
+	bob := &Client{identity: auth.Identity{Name: "bob"}, watchSince: map[string]time.Time{"hq-mayor": now.Add(-time.Minute), "hq-deacon": now}}
+	idle := &Client{identity: auth.Identity{Name: "carol"}, watchSince: map[string]time.Time{}}
+
+	s := &Server{clients: map[*Client]struct{}{alice: {}, bob: {}, idle: {}}}
+	s.inputLocks = newInputLocks(time.Minute, func(string, *Client, *Client, string) {})
+	if err := s.inputLocks.acquire("hq-mayor", alice, false); err != nil {
+		t.Fatal(err)
+	}
+
+	viewers := s.Presence("hq-mayor")
+	if len(viewers) != 2 || viewers[0].Name != "bob" || viewers[1].Name != "alice" {
+		t.Fatalf("Presence() = %+v, want bob then alice", viewers)
+	}
+	if viewers[1].Label != "dashboard" || !viewers[1].Driving || viewers[0].Driving {
+		t.Fatalf("Presence() = %+v, want alice labeled and driving", viewers)
+	}
+	if !s.Watched("hq-deacon") || s.Watched("gt-myrig-crew-bob") {
+		t.Fatal("Watched() mismatch")
+	}
+
+	presence := s.presenceFor([]string{"hq-mayor", "hq-deacon", "gt-myrig-crew-bob"})
+	if len(presence) != 2 || len(presence["hq-deacon"]) != 1 {
+		t.Fatalf("presenceFor() = %+v, want only watched agents", presence)
+	}
+}
+
+func TestClientLabelIsBounded(t *testing.T) {
+	if got := clientLabel("  vscode  "); got != "vscode" {
+		t.Fatalf("clientLabel() = %q", got)
+	}
+	if got := clientLabel(strings.Repeat("é", 100)); len([]rune(got)) != maxLabelLen {
+		t.Fatalf("clientLabel() kept %d runes, want %d", len([]rune(got)), maxLabelLen)
+	}
+}
@@ -0,0 +1,239 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ResizePolicy decides an agent's window size from its viewers' requests.
+type ResizePolicy string
+
+const (
+	ResizeLargest  ResizePolicy = "largest"     // the largest requested cols and rows
+	ResizeSmallest ResizePolicy = "smallest"    // the smallest requested cols and rows, so everyone sees the whole screen
+	ResizeDriver   ResizePolicy = "driver-wins" // the input lock holder's size, else the latest request
+	ResizeFixed    ResizePolicy = "fixed"       // a set size; viewer requests are ignored
+)
+
+// ParseResizePolicy parses a --resize-policy or set-resize-policy value.
+func ParseResizePolicy(s string) (ResizePolicy, error) {
+	switch p := ResizePolicy(s); p {
+	case ResizeLargest, ResizeSmallest, ResizeDriver, ResizeFixed:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid resize policy %q: expected largest, smallest, driver-wins or fixed", s)
+}
+
+type termSize struct {
+	cols, rows int
+}
+
+type sizeRequest struct {
+	size termSize
+	at   time.Time
+}
+
+// target computes the window size for a set of requests. It reports false
+// when there is nothing to apply.
+func (p ResizePolicy) target(requests map[*Client]sizeRequest, driver *Client, fixed termSize) (termSize, bool) {
+	if p == ResizeFixed {
+		return fixed, fixed.cols > 0 && fixed.rows > 0
+	}
+	if len(requests) == 0 {
+		return termSize{}, false
+	}
+	if p == ResizeDriver {
+		if req, ok := requests[driver]; ok {
+			return req.size, true
+		}
+		var latest sizeRequest
+		for _, req := range requests {
+			if req.at.After(latest.at) {
+				latest = req
+			}
+		}
+		return latest.size, true
+	}
+
+	var out termSize
+	first := true
+	for _, req := range requests {
+		switch {
+		case first:
+			out = req.size
+			first = false
+		case p == ResizeLargest:
+			out.cols = max(out.cols, req.size.cols)
+			out.rows = max(out.rows, req.size.rows)
+		default:
+			out.cols = min(out.cols, req.size.cols)
+			out.rows = min(out.rows, req.size.rows)
+		}
+	}
+	return out, true
+}
+
+// windowSizer is the tmux control used to apply window sizes.
+type windowSizer interface {
+	WindowSize(target string) (cols, rows int, err error)
+	ResizeWindow(target string, cols, rows int) error
+}
+
+// resizeArbiter tracks requested window sizes per agent and applies the
+// agent's resize policy. The window's original size is restored once no web
+// client is left watching or sizing it.
+type resizeArbiter struct {
+	defaultPolicy ResizePolicy
+
+	mu     sync.Mutex
+	agents map[string]*agentSize
+}
+
+type agentSize struct {
+	policy   ResizePolicy // "" = default policy
+	fixed    termSize
+	requests map[*Client]sizeRequest
+	applied  termSize
+	original *termSize // window size before the first web resize
+}
+
+func newResizeArbiter(defaultPolicy ResizePolicy) *resizeArbiter {
+	if defaultPolicy == "" {
+		defaultPolicy = ResizeDriver
+	}
+	return &resizeArbiter{defaultPolicy: defaultPolicy, agents: make(map[string]*agentSize)}
+}
+
+func (a *resizeArbiter) state(agent string) *agentSize {
+	st, ok := a.agents[agent]
+	if !ok {
+		st = &agentSize{requests: make(map[*Client]sizeRequest)}
+		a.agents[agent] = st
+	}
+	return st
+}
+
+// request records c's requested size for agent.
+func (a *resizeArbiter) request(agent string, c *Client, cols, rows int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state(agent).requests[c] = sizeRequest{size: termSize{cols, rows}, at: time.Now()}
+}
+
+// setPolicy sets agent's policy ("" = default); fixed is used by ResizeFixed.
+func (a *resizeArbiter) setPolicy(agent string, policy ResizePolicy, fixed termSize) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st := a.state(agent)
+	st.policy = policy
+	st.fixed = fixed
+}
+
+// forget drops c's request for agent.
+func (a *resizeArbiter) forget(agent string, c *Client) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if st, ok := a.agents[agent]; ok {
+		delete(st.requests, c)
+	}
+}
+
+// forgetClient drops all of c's requests and returns the affected agents.
+func (a *resizeArbiter) forgetClient(c *Client) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var affected []string
+	for agent, st := range a.agents {
+		if _, ok := st.requests[c]; ok {
+			delete(st.requests, c)
+			affected = append(affected, agent)
+		}
+	}
+	return affected
+}
+
+// drop forgets an agent that no longer exists.
+func (a *resizeArbiter) drop(agent string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.agents, agent)
+}
+
+// apply resizes the window holding agent's pane to its policy's target. With
+// no viewers and no outstanding requests, the original window size is
+// restored instead.
+func (a *resizeArbiter) apply(agent, pane string, viewers int, driver *Client, ctrl windowSizer) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st, ok := a.agents[agent]
+	if !ok {
+		return nil
+	}
+
+	if viewers == 0 && len(st.requests) == 0 {
+		var err error
+		if st.original != nil {
+			log.Printf("resize %s: last viewer left, restoring %dx%d", agent, st.original.cols, st.original.rows)
+			err = ctrl.ResizeWindow(pane, st.original.cols, st.original.rows)
+		}
+		st.original = nil
+		st.applied = termSize{}
+		if st.policy == "" {
+			delete(a.agents, agent)
+		}
+		return err
+	}
+
+	policy := st.policy
+	if policy == "" {
+		policy = a.defaultPolicy
+	}
+	target, ok := policy.target(st.requests, driver, st.fixed)
+	if !ok || target == st.applied {
+		return nil
+	}
+	if st.original == nil {
+		if cols, rows, err := ctrl.WindowSize(pane); err == nil {
+			st.original = &termSize{cols, rows}
+		} else {
+			log.Printf("resize %s: window size: %v", agent, err)
+		}
+	}
+	if err := ctrl.ResizeWindow(pane, target.cols, target.rows); err != nil {
+		return err
+	}
+	st.applied = target
+	return nil
+}
+
+// requestResize records c's size for agent and resizes the window to the
+// agent's policy. Only running agents' windows are resized.
+func (s *Server) requestResize(c *Client, agent string, cols, rows int) error {
+	if _, ok := s.registry.GetAgent(agent); !ok {
+		return errAgentNotFound
+	}
+	s.resizes.request(agent, c, cols, rows)
+	return s.applySize(agent)
+}
+
+// applySize recomputes and applies agent's window size from its viewers. An
+// agent that is gone has nothing left to resize.
+func (s *Server) applySize(agent string) error {
+	a, ok := s.registry.GetAgent(agent)
+	if !ok {
+		return nil
+	}
+	return s.resizes.apply(agent, a.Target(), len(s.Presence(agent)), s.inputLocks.holder(agent), s.sizer)
+}
+
+// resizeAgents re-applies each agent's size after its viewers or input lock
+// change, restoring the window once its last web viewer is gone.
+func (s *Server) resizeAgents(agents ...string) {
+	for _, agent := range agents {
+		if err := s.applySize(agent); err != nil {
+			log.Printf("resize %s error: %v", agent, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+// fakeWindow records resize-window calls against one window.
+type fakeWindow struct {
+	size    termSize
+	resizes int
+}
+
+func (w *fakeWindow) WindowSize(string) (int, int, error) {
+	return w.size.cols, w.size.rows, nil
+}
+
+func (w *fakeWindow) ResizeWindow(_ string, cols, rows int) error {
+	w.size = termSize{cols, rows}
+	w.resizes++
+	return nil
+}
+
+func TestResizePolicyTargets(t *testing.T) {
+	alice := &Client{identity: auth.Identity{Name: "alice"}}
+	bob := &Client{identity: auth.Identity{Name: "bob"}}
+	a := newResizeArbiter(ResizeLargest)
+	a.request("hq-mayor", alice, 120, 30)
+	a.request("hq-mayor", bob, 80, 50)
+	requests := a.agents["hq-mayor"].requests
+
+	tests := []struct {
+		policy ResizePolicy
+		driver *Client
+		want   termSize
+	}{
+		{ResizeLargest, nil, termSize{120, 50}},
+		{ResizeSmallest, nil, termSize{80, 30}},
+		{ResizeDriver, alice, termSize{120, 30}},
+		{ResizeDriver, nil, termSize{80, 50}}, // no driver: latest request
+		{ResizeFixed, nil, termSize{100, 40}},
+	}
+	for _, tt := range tests {
+		got, ok := tt.policy.target(requests, tt.driver, termSize{100, 40})
+		if !ok || got != tt.want {
+			t.Errorf("%s target = %v, %v; want %v", tt.policy, got, ok, tt.want)
+		}
+	}
+	if _, ok := ResizeLargest.target(nil, nil, termSize{}); ok {
+		t.Error("target with no requests should report nothing to apply")
+	}
+	if _, err := ParseResizePolicy("biggest"); err == nil {
+		t.Error("ParseResizePolicy accepted an unknown policy")
+	}
+}
+
+func TestResizeRestoresOriginalWhenViewersLeave(t *testing.T) {
+	alice := &Client{identity: auth.Identity{Name: "alice"}}
+	bob := &Client{identity: auth.Identity{Name: "bob"}}
+	win := &fakeWindow{size: termSize{200, 60}}
+	a := newResizeArbiter(ResizeSmallest)
+
+	a.request("hq-mayor", alice, 120, 40)
+	a.request("hq-mayor", bob, 100, 50)
+	if err := a.apply("hq-mayor", "%1", 2, nil, win); err != nil {
+		t.Fatal(err)
+	}
+	if win.size != (termSize{100, 40}) {
+		t.Fatalf("window = %v, want 100x40", win.size)
+	}
+
+	// Re-applying an unchanged target does not resize again.
+	if err := a.apply("hq-mayor", "%1", 2, nil, win); err != nil || win.resizes != 1 {
+		t.Fatalf("resizes = %d, err = %v; want 1 resize", win.resizes, err)
+	}
+
+	a.forget("hq-mayor", bob)
+	if err := a.apply("hq-mayor", "%1", 1, nil, win); err != nil {
+		t.Fatal(err)
+	}
+	if win.size != (termSize{120, 40}) {
+		t.Fatalf("window = %v after bob left, want 120x40", win.size)
+	}
+
+	if agents := a.forgetClient(alice); len(agents) != 1 || agents[0] != "hq-mayor" {
+		t.Fatalf("forgetClient() = %v", agents)
+	}
+	if err := a.apply("hq-mayor", "%1", 0, nil, win); err != nil {
+		t.Fatal(err)
+	}
+	if win.size != (termSize{200, 60}) {
+		t.Fatalf("window = %v after last viewer left, want original 200x60", win.size)
+	}
+	if _, ok := a.agents["hq-mayor"]; ok {
+		t.Fatal("state for an unwatched agent with the default policy should be dropped")
+	}
+}
+
+func TestBinaryResizeRequiresRunningAgent(t *testing.T) {
+	win := &fakeWindow{size: termSize{200, 60}}
+	c := &Client{
+		server: &Server{
+			registry:   agents.NewRegistry(nil, "", agents.DiscoveryRules{}),
+			resizes:    newResizeArbiter(ResizeDriver),
+			inputLocks: newInputLocks(time.Minute, nil),
+			sizer:      win,
+		},
+		send:     make(chan outMsg, 4),
+		identity: auth.Identity{Name: "ops", Scope: auth.ScopeAdmin},
+	}
+
+	for _, name := range []string{"adapter-monitor", "gt-myrig-crew-bob"} {
+		frame := append([]byte{BinaryResize}, name+"\x00120:40"...)
+		handleBinaryMessage(c, frame)
+		var resp Response
+		if err := json.Unmarshal((<-c.send).data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error != "resize "+name+": agent not found" {
+			t.Fatalf("%s: response = %+v, want agent not found", name, resp)
+		}
+	}
+	if win.resizes != 0 {
+		t.Fatalf("%d windows resized for names that are not agents", win.resizes)
+	}
+	if n := len(c.server.resizes.agents); n != 0 {
+		t.Fatalf("resize state kept for %d names that are not agents", n)
+	}
+}
+
+func TestSetResizePolicyRequiresRunningVisibleAgent(t *testing.T) {
+	c := &Client{
+		server: &Server{
+			registry: agents.NewRegistry(nil, "", agents.DiscoveryRules{}),
+			resizes:  newResizeArbiter(ResizeDriver),
+		},
+		send: make(chan outMsg, 4),
+		identity: auth.Identity{Name: "rig-a", Scope: auth.ScopeOperate, Rules: []auth.Rule{
+			{Rigs: []string{"rig-a"}},
+		}},
+	}
+
+	for _, name := range []string{"gt-rig-a-crew-bob", "gt-rig-b-crew-bob"} {
+		handleMessage(c, Request{ID: "1", Type: "set-resize-policy", Agent: name, Policy: "fixed", Cols: 80, Rows: 24})
+		var resp Response
+		if err := json.Unmarshal((<-c.send).data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.OK == nil || *resp.OK || resp.Error != "agent not found" {
+			t.Fatalf("%s: response = %+v, want agent not found", name, resp)
+		}
+	}
+	if n := len(c.server.resizes.agents); n != 0 {
+		t.Fatalf("resize state kept for %d agents that are not running", n)
+	}
+}
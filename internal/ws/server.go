@@ -2,15 +2,19 @@ package ws
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
-	"strings"
 	"sync"
+	"time"
 
 	"nhooyr.io/websocket"
 
 	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/audit"
 	"github.com/gastownhall/tmux-adapter/internal/auth"
+	"github.com/gastownhall/tmux-adapter/internal/lifecycle"
+	"github.com/gastownhall/tmux-adapter/internal/ratelimit"
 	"github.com/gastownhall/tmux-adapter/internal/tmux"
 )
 
@@ -19,33 +23,89 @@ type Server struct {
 	registry       *agents.Registry
 	pipeMgr        *tmux.PipePaneManager
 	ctrl           *tmux.ControlMode
-	authToken      string
+	life           *lifecycle.Manager
+	authn          *auth.Authenticator
 	originPatterns []string
+	compression    websocket.CompressionMode
+	audit          *audit.Logger       // nil when auditing is disabled
+	limits         *ratelimit.Limiters // nil when rate limiting is disabled
+	inputLocks     *inputLocks
+	resizes        *resizeArbiter
+	sizer          windowSizer // ctrl, for window sizes
+	viewersAttach  bool // agents streamed by a client count as attached for prompt delivery
 	clients        map[*Client]struct{}
 	mu             sync.Mutex
 }
 
+// Options configures a Server.
+type Options struct {
+	Registry       *agents.Registry
+	PipeMgr        *tmux.PipePaneManager
+	Ctrl           *tmux.ControlMode
+	Life           *lifecycle.Manager
+	Authn          *auth.Authenticator
+	OriginPatterns []string
+	Compression    websocket.CompressionMode
+	Audit          *audit.Logger       // nil disables auditing
+	Limits         *ratelimit.Limiters // nil disables rate limiting
+
+	// InputLockTimeout releases an input lock idle for this long.
+	InputLockTimeout time.Duration
+	// ViewersAttach counts agents streamed by a client as attached for
+	// prompt delivery.
+	ViewersAttach bool
+	// ResizePolicy arbitrates window size between viewers.
+	ResizePolicy ResizePolicy
+}
+
 // NewServer creates a new WebSocket server.
-func NewServer(registry *agents.Registry, pipeMgr *tmux.PipePaneManager, ctrl *tmux.ControlMode, authToken string, originPatterns []string) *Server {
-	return &Server{
-		registry:       registry,
-		pipeMgr:        pipeMgr,
-		ctrl:           ctrl,
-		authToken:      strings.TrimSpace(authToken),
-		originPatterns: originPatterns,
+func NewServer(opts Options) *Server {
+	s := &Server{
+		registry:       opts.Registry,
+		pipeMgr:        opts.PipeMgr,
+		ctrl:           opts.Ctrl,
+		life:           opts.Life,
+		authn:          opts.Authn,
+		originPatterns: opts.OriginPatterns,
+		compression:    opts.Compression,
+		audit:          opts.Audit,
+		limits:         opts.Limits,
+		viewersAttach:  opts.ViewersAttach,
+		resizes:        newResizeArbiter(opts.ResizePolicy),
+		sizer:          opts.Ctrl,
 		clients:        make(map[*Client]struct{}),
 	}
+	s.inputLocks = newInputLocks(opts.InputLockTimeout, func(agent string, holder, prev *Client, reason string) {
+		s.broadcastInputLock(agent, holder, prev, reason)
+		s.resizeAgents(agent) // driver-wins follows the new holder
+	})
+	return s
 }
 
 // ServeHTTP handles WebSocket upgrade requests at /ws.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !auth.IsAuthorizedRequest(s.authToken, r) {
+	if blocked, wait := s.limits.AuthBlocked(r); blocked {
+		w.Header().Set("Retry-After", ratelimit.RetryAfter(wait))
+		http.Error(w, "too many failed authentication attempts", http.StatusTooManyRequests)
+		return
+	}
+	identity, ok := s.authn.Authenticate(r)
+	if !ok {
+		s.limits.AuthFailed(r)
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	// permessage-deflate is only used if the client offers it; a client can
+	// also opt out with ?compression=off (e.g. to save CPU on a fast LAN).
+	compression := s.compression
+	if r.URL.Query().Get("compression") == "off" {
+		compression = websocket.CompressionDisabled
+	}
+
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: s.originPatterns,
+		OriginPatterns:  s.originPatterns,
+		CompressionMode: compression,
 	})
 	if err != nil {
 		log.Printf("websocket accept: %v", err)
@@ -54,7 +114,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	conn.SetReadLimit(int64(maxFileUploadBytes + 64*1024))
 
 	ctx, cancel := context.WithCancel(r.Context())
-	client := NewClient(conn, s, ctx, cancel)
+	client := NewClient(conn, s, identity, ctx, cancel)
+	client.remote = r.RemoteAddr
+	client.label = clientLabel(r.URL.Query().Get("label"))
 
 	s.mu.Lock()
 	s.clients[client] = struct{}{}
@@ -70,22 +132,59 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.RemoveClient(client)
 }
 
-// BroadcastToAgentSubscribers sends a message to all clients subscribed to agent lifecycle events.
-func (s *Server) BroadcastToAgentSubscribers(msg []byte) {
+// ParseCompressionMode maps a --ws-compression flag value to a websocket
+// compression mode.
+func ParseCompressionMode(s string) (websocket.CompressionMode, error) {
+	switch s {
+	case "off", "disabled":
+		return websocket.CompressionDisabled, nil
+	case "no-context-takeover":
+		return websocket.CompressionNoContextTakeover, nil
+	case "context-takeover":
+		return websocket.CompressionContextTakeover, nil
+	}
+	return websocket.CompressionDisabled, fmt.Errorf("invalid compression mode %q: expected off, no-context-takeover or context-takeover", s)
+}
+
+// BroadcastToAgentSubscribers sends a lifecycle event about agent to all
+// clients subscribed to agent lifecycle events that are allowed to see it.
+func (s *Server) BroadcastToAgentSubscribers(agent agents.Agent, msg []byte) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	ref := auth.RefOf(agent)
 	for client := range s.clients {
 		client.mu.Lock()
 		subscribed := client.agentSub
 		client.mu.Unlock()
 
-		if subscribed {
+		if subscribed && client.identity.CanSee(ref) {
 			client.SendText(msg)
 		}
 	}
 }
 
+// broadcastInputLock tells every client watching an agent (subscribed to its
+// output or to lifecycle events), and the old and new holders, who now holds
+// its input lock.
+func (s *Server) broadcastInputLock(agent string, holder, prev *Client, reason string) {
+	ref := s.agentRef(agent)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		if (client.watching(agent) || client == holder || client == prev) && client.identity.CanSee(ref) {
+			client.sendInputLock(agent, holder, reason)
+		}
+	}
+}
+
+// AgentRemoved drops an agent's input lock and resize state.
+func (s *Server) AgentRemoved(agent string) {
+	s.inputLocks.releaseAgent(agent)
+	s.resizes.drop(agent)
+}
+
 // RemoveClient unsubscribes and removes a client from the server.
 func (s *Server) RemoveClient(client *Client) {
 	s.mu.Lock()
@@ -93,7 +192,19 @@ func (s *Server) RemoveClient(client *Client) {
 	count := len(s.clients)
 	s.mu.Unlock()
 
+	client.mu.Lock()
+	watched := make([]string, 0, len(client.watchSince))
+	for agent := range client.watchSince {
+		watched = append(watched, agent)
+	}
+	client.mu.Unlock()
+
 	client.Close()
+	s.inputLocks.releaseClient(client)
+	for _, agent := range watched {
+		s.broadcastPresence(agent)
+	}
+	s.resizeAgents(append(watched, s.resizes.forgetClient(client)...)...)
 	log.Printf("client disconnected (%d remaining)", count)
 }
 
@@ -6,27 +6,115 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gastownhall/tmux-adapter/internal/adapter"
+	"github.com/gastownhall/tmux-adapter/internal/agents"
+	"github.com/gastownhall/tmux-adapter/internal/history"
+	"github.com/gastownhall/tmux-adapter/internal/tmux"
+	"github.com/gastownhall/tmux-adapter/internal/ws"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "mint-token" {
+		os.Exit(mintToken(os.Args[2:]))
+	}
+
 	gtDir := flag.String("gt-dir", filepath.Join(os.Getenv("HOME"), "gt"), "gastown town directory")
 	port := flag.Int("port", 8080, "WebSocket server port")
 	authToken := flag.String("auth-token", "", "optional WebSocket auth token (Bearer token or ?token=...)")
+	authTokensFile := flag.String("auth-tokens-file", "", "JSON file of named API tokens with read/operate/admin scopes")
+	signingKeyFile := flag.String("token-signing-key-file", "", "HMAC-SHA256 key file for short-lived signed tokens (POST /api/tokens, mint-token)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (PEM); serves HTTPS/WSS and reloads on change")
+	tlsKey := flag.String("tls-key", "", "TLS private key file (PEM)")
+	tlsClientCA := flag.String("tls-client-ca", "", "CA bundle for verifying client certificates; subjects map to tokens-file identities")
+	unixSocket := flag.String("unix-socket", "", "also serve on this Unix socket; same-user callers are trusted, others need a tokens-file peer entry")
+	unixSocketMode := flag.String("unix-socket-mode", "0600", "permissions of the Unix socket file (octal)")
 	allowedOrigins := flag.String("allowed-origins", "localhost:*", "comma-separated origin patterns for WebSocket CORS (e.g. \"localhost:*,myhost.example.com\")")
+	historyEnabled := flag.Bool("history", false, "record ANSI-stripped agent transcripts to disk for search")
+	historyDir := flag.String("history-dir", "", "transcript directory (default <gt-dir>/.tmux-adapter/history)")
+	historyMaxAge := flag.Duration("history-max-age", 7*24*time.Hour, "delete transcript segments older than this (0 = keep forever)")
+	historyMaxBytes := flag.Int64("history-max-bytes", 256<<20, "per-agent transcript size limit in bytes (0 = unlimited)")
+	rateLimit := flag.Bool("rate-limit", true, "rate-limit REST calls, WebSocket messages, uploads and failed auth attempts")
+	rateLimitsFile := flag.String("rate-limits-file", "", "JSON file overriding the default per-scope rate limits")
+	adhocSessions := flag.String("adhoc-sessions", "", "comma-separated session name globs tracked as ad-hoc agents outside gastown (e.g. \"claude-*,dev-*\")")
+	adhocEnv := flag.String("adhoc-env", "", "comma-separated session environment variables that mark a session as an ad-hoc agent")
+	adhocAnyRuntime := flag.Bool("adhoc-any-runtime", false, "track every session with a pane running a known agent runtime as an ad-hoc agent")
+	scanInterval := flag.Duration("scan-interval", agents.DefaultScanInterval, "rescan tmux this often to catch agents exiting inside a live session (0 = only on tmux notifications)")
+	restartPoliciesFile := flag.String("restart-policies-file", "", "JSON file of per-role and per-agent restart policies (never, on-failure, always)")
+	auditLog := flag.String("audit-log", "", "append-only JSONL audit log of prompts, input, uploads, resizes and kills (disabled if empty)")
+	auditMaxBytes := flag.Int64("audit-max-bytes", 64<<20, "rotate the audit log when it exceeds this size")
+	auditMaxFiles := flag.Int("audit-max-files", 5, "number of rotated audit logs to keep")
+	outputMinInterval := flag.Duration("output-min-interval", tmux.DefaultFlushPolicy.MinInterval, "output batching delay after a quiet period (keystroke echo latency)")
+	outputMaxInterval := flag.Duration("output-max-interval", tmux.DefaultFlushPolicy.MaxInterval, "maximum output batching interval during bursts")
+	wsCompression := flag.String("ws-compression", "no-context-takeover", "WebSocket permessage-deflate mode: off, no-context-takeover, context-takeover")
+	inputLockTimeout := flag.Duration("input-lock-timeout", ws.DefaultInputLockTimeout, "release a WebSocket input lock after this long without input from its holder")
+	webViewersAttach := flag.Bool("web-viewers-attach", false, "treat agents streamed by a WebSocket client as attached (prompts skip the SIGWINCH wake)")
+	resizePolicy := flag.String("resize-policy", string(ws.ResizeDriver), "how WebSocket viewers' sizes set an agent's window: largest, smallest, driver-wins, fixed")
 	flag.Parse()
 
-	var origins []string
-	for _, o := range strings.Split(*allowedOrigins, ",") {
-		if s := strings.TrimSpace(o); s != "" {
-			origins = append(origins, s)
-		}
+	compression, err := ws.ParseCompressionMode(*wsCompression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	policy, err := ws.ParseResizePolicy(*resizePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	socketMode, err := strconv.ParseUint(*unixSocketMode, 8, 32)
+	if err != nil {
+		log.Fatalf("invalid --unix-socket-mode %q: expected octal permissions", *unixSocketMode)
 	}
 
-	a := adapter.New(*gtDir, *port, *authToken, origins)
+	origins := splitList(*allowedOrigins)
+
+	a := adapter.New(adapter.Config{
+		GtDir:          *gtDir,
+		Port:           *port,
+		AuthToken:      *authToken,
+		AuthTokensFile: *authTokensFile,
+		SigningKeyFile: *signingKeyFile,
+		OriginPatterns: origins,
+		TLSCert:        *tlsCert,
+		TLSKey:         *tlsKey,
+		TLSClientCA:    *tlsClientCA,
+		UnixSocket:     *unixSocket,
+		UnixSocketMode: os.FileMode(socketMode),
+		FlushPolicy: tmux.FlushPolicy{
+			MinInterval: *outputMinInterval,
+			MaxInterval: *outputMaxInterval,
+		},
+		WSCompression:    compression,
+		InputLockTimeout: *inputLockTimeout,
+		WebViewersAttach: *webViewersAttach,
+		ResizePolicy:     policy,
+		History:          *historyEnabled,
+		HistoryDir:       *historyDir,
+		HistoryRetention: history.Retention{
+			MaxAge:   *historyMaxAge,
+			MaxBytes: *historyMaxBytes,
+		},
+		RateLimit:      *rateLimit,
+		RateLimitsFile: *rateLimitsFile,
+
+		Discovery: agents.DiscoveryRules{
+			Sessions:   splitList(*adhocSessions),
+			EnvMarkers: splitList(*adhocEnv),
+			AnyRuntime: *adhocAnyRuntime,
+		},
+		ScanInterval: *scanInterval,
+
+		RestartPoliciesFile: *restartPoliciesFile,
+
+		AuditLog:      *auditLog,
+		AuditMaxBytes: *auditMaxBytes,
+		AuditMaxFiles: *auditMaxFiles,
+	})
 	if err := a.Start(); err != nil {
 		log.Fatal(err)
 	}
@@ -38,3 +126,14 @@ func main() {
 
 	a.Stop()
 }
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if s := strings.TrimSpace(item); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
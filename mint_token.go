@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gastownhall/tmux-adapter/internal/auth"
+)
+
+// mintToken implements `tmux-adapter mint-token`: sign a short-lived access
+// token offline with the same key the server uses.
+func mintToken(args []string) int {
+	fs := flag.NewFlagSet("mint-token", flag.ContinueOnError)
+	keyFile := fs.String("key-file", "", "HMAC-SHA256 key file (same as the server's --token-signing-key-file)")
+	name := fs.String("name", "", "token subject, shown as the caller's identity")
+	scope := fs.String("scope", "read", "read, operate or admin")
+	ttl := fs.Duration("ttl", 15*time.Minute, "token lifetime")
+	agentPatterns := fs.String("agents", "", "comma-separated agent name globs the token is limited to (default: all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *keyFile == "" || *name == "" {
+		fmt.Fprintln(os.Stderr, "mint-token: --key-file and --name are required")
+		fs.Usage()
+		return 2
+	}
+
+	key, err := auth.LoadSigningKeyFile(*keyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mint-token:", err)
+		return 1
+	}
+	signer, err := auth.NewSigner(key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mint-token:", err)
+		return 1
+	}
+	s, err := auth.ParseScope(*scope)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mint-token:", err)
+		return 2
+	}
+
+	claims := auth.Claims{Subject: *name, Scope: s, Issuer: "mint-token"}
+	var patterns []string
+	for _, p := range strings.Split(*agentPatterns, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) > 0 {
+		claims.Rules = []auth.Rule{{Agents: patterns}}
+	}
+
+	token, err := signer.Mint(claims, time.Now(), *ttl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mint-token:", err)
+		return 1
+	}
+	fmt.Println(token)
+	return 0
+}